@@ -10,11 +10,17 @@ import (
 	"os/signal"
 	"runtime"
 
+	"github.com/gofu/gomon/highlight/highlightfs"
+	"github.com/gofu/gomon/profiler/history"
+	"github.com/gofu/gomon/profiler/poller"
 	"github.com/gofu/gomon/server"
+	"github.com/gofu/gomon/style"
 )
 
 func main() {
 	var s server.Server
+	var printMode bool
+	var printOpts server.PrintOptions
 	flag.StringVar(&s.Addr, "addr", "127.0.0.1:7656", "HTTP listen address")
 	flag.StringVar(&s.PProfURL, "url", "http://127.0.0.1:7656/debug/pprof", "Remote /debug/pprof URL")
 	flag.StringVar(&s.Local.Root, "local-root", currentDir(), "Local project root")
@@ -23,14 +29,43 @@ func main() {
 	flag.StringVar(&s.Remote.Root, "remote-root", "", "Remote project root")
 	flag.StringVar(&s.Remote.GoRoot, "remote-goroot", "", "Remote GOROOT")
 	flag.StringVar(&s.Remote.GoPath, "remote-gopath", "", "Remote GOPATH")
+	flag.StringVar(&s.SourceArchive, "source-archive", "", "Source .zip/.tar.gz archive to read project sources from")
+	flag.StringVar(&s.Archive.Root, "archive-root", "", "Project root inside -source-archive")
+	flag.StringVar(&s.Archive.GoRoot, "archive-goroot", "", "GOROOT inside -source-archive, local GOROOT if empty")
+	flag.StringVar(&s.Archive.GoPath, "archive-gopath", "", "GOPATH inside -source-archive, local GOPATH if empty")
+	flag.StringVar(&s.SourceURL, "source-url", "", "Remote source handler URL, eg. http://127.0.0.1:6060/debug/pprof/source")
+	flag.StringVar(&s.GoVersion, "remote-go-version", "", `Remote Go version for GOROOT sources, eg. go1.21.3; detected if empty, "off" uses -local-goroot`)
+	flag.StringVar(&s.BuildInfoURL, "buildinfo-url", "", "Remote URL serving (*debug.BuildInfo).String output, to detect remote Go version")
+	flag.Int64Var(&s.CacheSize, "cache-size", highlightfs.DefaultCacheSize, "Maximum total size of cached source files in bytes")
+	flag.BoolVar(&s.GitBlame, "git-blame", false, "Annotate project frames with git blame of -local-root")
+	flag.BoolVar(&s.GoToDefinition, "godef", false, "Type-check -local-root sources in the background, to link identifiers to their definitions")
+	flag.StringVar(&s.Editor, "editor", "", `Editor deep link to open frames: vscode, goland, idea, sublime, or a template with {abs} and {line}, eg. "http://localhost:8091/open?file={abs}&line={line}"`)
+	flag.DurationVar(&s.LiveInterval, "live-interval", poller.DefaultInterval, "Interval between polls of the live page, while it's open")
+	flag.DurationVar(&s.History, "history", 0, "Retention of goroutine counts charted on the index and summary pages, eg. 6h; polls the remote continuously, 0 disables")
+	flag.DurationVar(&s.HistoryInterval, "history-interval", history.DefaultInterval, "Interval between polls recorded in -history")
+	flag.BoolVar(&printMode, "print", false, "Print running goroutines with source code to the terminal, and exit")
+	flag.IntVar(&printOpts.Lines, "print-lines", 2, "Source lines shown before/after each frame with -print, negative disables")
+	flag.BoolVar(&printOpts.TrueColor, "truecolor", isTrueColor(), "Use 24-bit colors with -print, otherwise 256 colors")
+	flag.StringVar(&printOpts.Theme, "theme", style.Default, "Highlighting theme with -print")
 	flag.Parse()
 	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt)
-	err := server.ListenAndServe(ctx, s)
+	var err error
+	if printMode {
+		err = server.Print(ctx, s, printOpts, os.Stdout)
+	} else {
+		err = server.ListenAndServe(ctx, s)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// isTrueColor reports whether the terminal advertises 24-bit color support.
+func isTrueColor() bool {
+	colorTerm := os.Getenv("COLORTERM")
+	return colorTerm == "truecolor" || colorTerm == "24bit"
+}
+
 func currentDir() string {
 	wd, _ := os.Getwd()
 	return wd
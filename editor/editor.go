@@ -0,0 +1,109 @@
+// Package editor resolves call stack frames to local files,
+// and links them to an editor via deep link URL templates.
+package editor
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gofu/gomon/env"
+	"github.com/gofu/gomon/profiler"
+)
+
+// Template is a deep link URL template, where {abs} is replaced with
+// the absolute local file path, and {line} with the line number.
+// If {abs} is part of the URL query, it's query escaped, otherwise
+// each path element is path escaped, and a slash preceding {abs}
+// is not repeated for Unix paths.
+type Template string
+
+// Presets contains templates of common editors, by name.
+var Presets = map[string]Template{
+	"vscode":  "vscode://file/{abs}:{line}",
+	"goland":  "goland://open?file={abs}&line={line}",
+	"idea":    "idea://open?file={abs}&line={line}",
+	"sublime": "subl://open?url=file://{abs}&line={line}",
+}
+
+// Parse returns a preset template by name, or s as a custom
+// template, eg. http://localhost:8091/open?file={abs}&line={line}.
+func Parse(s string) (Template, error) {
+	if len(s) == 0 {
+		return "", nil
+	}
+	if t, ok := Presets[s]; ok {
+		return t, nil
+	}
+	if !strings.Contains(s, "{abs}") {
+		return "", fmt.Errorf("editor template %q: missing {abs}", s)
+	}
+	if _, err := url.Parse(s); err != nil {
+		return "", fmt.Errorf("editor template %q: %w", s, err)
+	}
+	return Template(s), nil
+}
+
+// URL returns the deep link of an absolute file path and line,
+// or an empty string if t or abs are empty.
+func (t Template) URL(abs string, line int) string {
+	if len(t) == 0 || len(abs) == 0 {
+		return ""
+	}
+	s := string(t)
+	absPos := strings.Index(s, "{abs}")
+	if q := strings.IndexByte(s, '?'); q != -1 && q < absPos {
+		abs = url.QueryEscape(abs)
+	} else {
+		if absPos > 0 && s[absPos-1] == '/' {
+			// eg. vscode://file/{abs} with abs=/home/user/main.go
+			abs = strings.TrimPrefix(abs, "/")
+		}
+		elems := strings.Split(abs, "/")
+		for i, elem := range elems {
+			elems[i] = url.PathEscape(elem)
+		}
+		abs = strings.Join(elems, "/")
+	}
+	return strings.NewReplacer("{abs}", abs, "{line}", strconv.Itoa(line)).Replace(s)
+}
+
+// Linker resolves frames against local environment paths.
+type Linker struct {
+	// Env contains local root paths.
+	Env env.Env
+	// Template of editor deep links, disabled if empty.
+	Template Template
+}
+
+// Abs returns the absolute local path of a frame,
+// or an empty string if its root is unknown.
+func (l Linker) Abs(file profiler.FileLine) string {
+	root := l.Env.RootPath(file.Root)
+	if len(root) == 0 || len(file.File) == 0 {
+		return ""
+	}
+	return path.Join(root, file.File)
+}
+
+// Resolve sets profiler.CallStack.Abs on frames of goroutines.
+func (l Linker) Resolve(goroutines []profiler.Goroutine) {
+	for _, gr := range goroutines {
+		for j := range gr.CallStack {
+			s := &gr.CallStack[j]
+			s.Abs = l.Abs(s.FileLine)
+		}
+	}
+}
+
+// URL returns the editor deep link of a frame,
+// or an empty string if it cannot be linked.
+func (l Linker) URL(s profiler.CallStack) string {
+	abs := s.Abs
+	if len(abs) == 0 {
+		abs = l.Abs(s.FileLine)
+	}
+	return l.Template.URL(abs, s.Line)
+}
@@ -58,7 +58,9 @@ func (e Env) FileLocation(file string) (profiler.RootType, string, error) {
 		return profiler.RootTypeGoRoot, f, nil
 	} else if f, ok = cutPrefix(file, e.GoPath); ok {
 		return profiler.RootTypeGoPath, f, nil
-	} else if strings.HasPrefix(file, "_cgo_") {
+	} else if strings.HasPrefix(path.Base(file), "_cgo_") {
+		// generated in a temporary build directory, eg. /tmp/go-build123/b001/_cgo_export.c,
+		// which is kept by "go build -work"
 		return profiler.RootTypeCGo, file, nil
 	} else {
 		return "", "", fmt.Errorf("unknown component path: %q", file)
@@ -0,0 +1,260 @@
+// Package gitblame annotates project call stack frames with
+// the last change of their line, from the local git repository.
+package gitblame
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofu/gomon/profiler"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// headInterval is the minimum interval between resolving HEAD revision.
+	headInterval = 2 * time.Second
+	// blameTimeout bounds git blame of a file, shared by concurrent callers.
+	blameTimeout = time.Minute
+)
+
+// Blamer returns the last change of a project file line.
+type Blamer interface {
+	// Blame returns nil if the line is not known to version control.
+	Blame(context.Context, profiler.FileLine) (*profiler.Blame, error)
+}
+
+// Repo runs git blame in a local repository, caching results per file
+// and revision. Uncommitted changes are blamed as well, so files are
+// also re-blamed when their modification time changes.
+type Repo struct {
+	dir string
+	sf  singleflight.Group
+	mu  sync.Mutex
+	// head is the HEAD revision, resolved at headChecked
+	head        string
+	headChecked time.Time
+	// files contains blamed lines of files at head
+	files map[fileKey][]*profiler.Blame
+}
+
+// fileKey identifies a blamed file.
+type fileKey struct {
+	name    string
+	rev     string
+	modTime time.Time
+}
+
+// New returns Repo blaming files relative to dir, which
+// must be within a git repository with at least one commit.
+func New(dir string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, err
+	}
+	r := &Repo{dir: dir}
+	if _, err := r.revision(context.Background()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Blame returns the last change of a project file line.
+func (r *Repo) Blame(ctx context.Context, file profiler.FileLine) (*profiler.Blame, error) {
+	if file.Root != profiler.RootTypeProject || len(file.File) == 0 {
+		return nil, nil
+	}
+	rev, err := r.revision(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filepath.Join(r.dir, filepath.FromSlash(file.File)))
+	if err != nil {
+		return nil, nil
+	}
+	key := fileKey{name: file.File, rev: rev, modTime: info.ModTime()}
+	r.mu.Lock()
+	lines, ok := r.files[key]
+	r.mu.Unlock()
+	if !ok {
+		// not bound to ctx, so callers waiting for the same
+		// file don't fail if the first one is canceled
+		ch := r.sf.DoChan(fmt.Sprint(key), func() (any, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), blameTimeout)
+			defer cancel()
+			return r.blameFile(ctx, key)
+		})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res := <-ch:
+			if res.Err != nil {
+				return nil, res.Err
+			}
+			lines = res.Val.([]*profiler.Blame)
+		}
+	}
+	if file.Line < 1 || file.Line > len(lines) {
+		return nil, nil
+	}
+	return lines[file.Line-1], nil
+}
+
+// blameFile runs git blame on a file and caches the result.
+// Files unknown to git are cached as having no lines.
+func (r *Repo) blameFile(ctx context.Context, key fileKey) ([]*profiler.Blame, error) {
+	out, err := r.git(ctx, "blame", "--porcelain", "--", key.name)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// eg. untracked file
+		out, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines, err := parsePorcelain(out)
+	if err != nil {
+		return nil, fmt.Errorf("git blame %s: %w", key.name, err)
+	}
+	r.mu.Lock()
+	for k := range r.files {
+		if k.name == key.name || k.rev != key.rev {
+			// outdated
+			delete(r.files, k)
+		}
+	}
+	if r.files == nil {
+		r.files = map[fileKey][]*profiler.Blame{}
+	}
+	r.files[key] = lines
+	r.mu.Unlock()
+	return lines, nil
+}
+
+// revision returns HEAD revision, resolved at most once per headInterval.
+func (r *Repo) revision(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	head, checked := r.head, r.headChecked
+	r.mu.Unlock()
+	if len(head) != 0 && time.Since(checked) < headInterval {
+		return head, nil
+	}
+	out, err := r.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	head = strings.TrimSpace(string(out))
+	r.mu.Lock()
+	r.head, r.headChecked = head, time.Now()
+	r.mu.Unlock()
+	return head, nil
+}
+
+// git runs a git command in the repository directory.
+func (r *Repo) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// parsePorcelain parses git blame --porcelain output into blame of each line.
+func parsePorcelain(out []byte) ([]*profiler.Blame, error) {
+	var lines []*profiler.Blame
+	commits := map[string]*profiler.Blame{}
+	var current *profiler.Blame
+	s := bufio.NewScanner(bytes.NewReader(out))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "\t") {
+			// line content follows commit headers
+			lines = append(lines, current)
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		if (len(key) == 40 || len(key) == 64) && isHex(key) {
+			// <commit> <original line> <final line> [<lines>]
+			b, ok := commits[key]
+			if !ok {
+				b = &profiler.Blame{Commit: key}
+				commits[key] = b
+			}
+			current = b
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("unexpected line %q", line)
+		}
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-mail":
+			current.Email = strings.Trim(value, "<>")
+		case "author-time":
+			sec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			current.Date = time.Unix(sec, 0)
+		case "author-tz":
+			if tz, err := time.Parse("-0700", value); err == nil {
+				current.Date = current.Date.In(tz.Location())
+			}
+		case "summary":
+			current.Summary = value
+		}
+	}
+	return lines, s.Err()
+}
+
+// isHex reports whether s contains only lowercase hex digits.
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Goroutines sets profiler.CallStack.Blame on project frames of goroutines.
+// Frames at the same file/line are blamed only once.
+func Goroutines(ctx context.Context, goroutines []profiler.Goroutine, blamer Blamer) error {
+	blamed := map[profiler.FileLine]*profiler.Blame{}
+	for _, gr := range goroutines {
+		for j := range gr.CallStack {
+			s := &gr.CallStack[j]
+			if s.Root != profiler.RootTypeProject {
+				continue
+			}
+			blame, ok := blamed[s.FileLine]
+			if !ok {
+				var err error
+				blame, err = blamer.Blame(ctx, s.FileLine)
+				if err != nil {
+					return err
+				}
+				blamed[s.FileLine] = blame
+			}
+			s.Blame = blame
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package toolchain finds local Go toolchains, matching the Go version of a remote binary.
+package toolchain
+
+import (
+	"bufio"
+	"bytes"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+)
+
+// versionRegexp matches Go release versions, eg. "go1.21", "go1.21.3" or "go1.22rc1".
+var versionRegexp = regexp.MustCompile(`^go1(\.\d+){0,2}((rc|beta)\d+)?$`)
+
+// ErrNotFound is returned when no local toolchain matches the Go version.
+var ErrNotFound = errors.New("matching Go toolchain not found")
+
+// Find returns the GOROOT of a local Go toolchain of the exact version, eg. "go1.21.3".
+// Toolchains installed by golang.org/dl in ~/sdk/go1.x.y are searched first, then the
+// toolchains downloaded by the go command to the module cache.
+func Find(version string) (string, error) {
+	if !versionRegexp.MatchString(version) {
+		return "", fmt.Errorf("invalid Go version: %q", version)
+	}
+	var candidates []string
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, "sdk", version))
+	}
+	if modCache := goModCache(); len(modCache) != 0 {
+		// source files do not depend on the GOOS/GOARCH the toolchain was downloaded for
+		matches, _ := filepath.Glob(filepath.Join(modCache, "golang.org", "toolchain@v0.0.1-"+version+".*"))
+		candidates = append(candidates, matches...)
+	}
+	for _, goRoot := range candidates {
+		if v, err := GoRootVersion(goRoot); err == nil && v == version {
+			return goRoot, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, version)
+}
+
+// GoRootVersion returns the Go version of GOROOT, read from its VERSION file.
+func GoRootVersion(goRoot string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(goRoot, "VERSION"))
+	if err != nil {
+		return "", err
+	}
+	version, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(version), nil
+}
+
+// BinaryVersion returns the Go version that a local binary was built with.
+func BinaryVersion(file string) (string, error) {
+	info, err := buildinfo.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return info.GoVersion, nil
+}
+
+// ParseVersion returns the Go version from the textual build info, as printed by
+// (*debug.BuildInfo).String or "go version -m", or a plain version string.
+func ParseVersion(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	if versionRegexp.Match(data) {
+		return string(data), nil
+	}
+	if info, err := debug.ParseBuildInfo(string(data)); err == nil && len(info.GoVersion) != 0 {
+		return info.GoVersion, nil
+	}
+	// "go version -m" output starts with "<path>: go1.x.y"
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		for _, field := range strings.Fields(s.Text()) {
+			if versionRegexp.MatchString(field) {
+				return field, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Go version not found in build info")
+}
+
+// goModCache returns GOMODCACHE, as reported by the go command if available.
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); len(dir) != 0 {
+		return dir
+	}
+	if out, err := exec.Command("go", "env", "GOMODCACHE").Output(); err == nil {
+		if dir := strings.TrimSpace(string(out)); len(dir) != 0 {
+			return dir
+		}
+	}
+	if goPath := os.Getenv("GOPATH"); len(goPath) != 0 {
+		return filepath.Join(filepath.SplitList(goPath)[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package highlight
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/gofu/gomon/profiler"
+)
+
+// Anchor links a span of a source code line, eg. an identifier to its definition.
+type Anchor struct {
+	// Line number, starting from 1.
+	Line int
+	// Col and End are byte columns of the span [Col,End), starting from 1.
+	Col, End int
+	// URL the span links to.
+	URL string
+	// Title shown on hover, eg. type of the identifier.
+	Title string
+}
+
+// Anchors provides anchors of source files.
+type Anchors interface {
+	// Anchors returns anchors on lines first to last of file, inclusive.
+	Anchors(file profiler.FileLine, first, last int) []Anchor
+}
+
+// Private use characters delimit anchored token values,
+// until they're replaced with HTML links after formatting.
+const (
+	anchorStart = "\ue000"
+	anchorHREF  = "\ue001"
+	anchorEnd   = "\ue002"
+)
+
+var anchorRegexp = regexp.MustCompile(anchorStart + `(\d+)` + anchorHREF)
+
+// markAnchors returns tokens of lines starting from baseLine, where
+// tokens matching an anchor span exactly are delimited by markers.
+func markAnchors(tokens []chroma.Token, baseLine int, anchors []Anchor) []chroma.Token {
+	if len(anchors) == 0 {
+		return tokens
+	}
+	type pos struct{ line, col int }
+	byPos := make(map[pos]int, len(anchors))
+	for i, a := range anchors {
+		byPos[pos{a.Line, a.Col}] = i
+	}
+	marked := make([]chroma.Token, len(tokens))
+	line, col := baseLine, 1
+	for i, tok := range tokens {
+		marked[i] = tok
+		if lf := strings.LastIndexByte(tok.Value, '\n'); lf != -1 {
+			line += strings.Count(tok.Value, "\n")
+			col = len(tok.Value) - lf
+			continue
+		}
+		if a, ok := byPos[pos{line, col}]; ok && anchors[a].End == col+len(tok.Value) {
+			marked[i].Value = anchorStart + strconv.Itoa(a) + anchorHREF + tok.Value + anchorEnd
+		}
+		col += len(tok.Value)
+	}
+	return marked
+}
+
+// linkAnchors replaces anchor markers in formatted HTML with links.
+func linkAnchors(formatted string, anchors []Anchor) string {
+	if len(anchors) == 0 {
+		return formatted
+	}
+	formatted = anchorRegexp.ReplaceAllStringFunc(formatted, func(s string) string {
+		i, err := strconv.Atoi(s[len(anchorStart) : len(s)-len(anchorHREF)])
+		if err != nil || i >= len(anchors) {
+			return ""
+		}
+		a := anchors[i]
+		return `<a href="` + html.EscapeString(a.URL) + `" title="` + html.EscapeString(a.Title) + `">`
+	})
+	return strings.ReplaceAll(formatted, anchorEnd, "</a>")
+}
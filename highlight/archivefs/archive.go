@@ -0,0 +1,77 @@
+// Package archivefs provides read-only fs.FS access to source code archives.
+package archivefs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gofu/gomon/highlight/internal/memfs"
+)
+
+// Open reads the whole .zip, .tar.gz, .tgz or .tar archive into memory,
+// and returns it as a read-only fs.FS. Archive format is determined by
+// the name extension.
+func Open(name string) (fs.FS, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		defer func() { _ = gz.Close() }()
+		return readTar(gz)
+	case strings.HasSuffix(lower, ".tar"):
+		return readTar(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %q", name)
+	}
+}
+
+// Sub returns the sub-tree of fsys rooted at dir. Leading and trailing
+// slashes are ignored, and an empty dir returns fsys unchanged.
+func Sub(fsys fs.FS, dir string) (fs.FS, error) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if len(dir) == 0 {
+		return fsys, nil
+	}
+	return fs.Sub(fsys, dir)
+}
+
+// readTar reads all regular files from an uncompressed tar stream.
+func readTar(r io.Reader) (fs.FS, error) {
+	files := memfs.FS{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", hdr.Name, err)
+		}
+		name := strings.TrimLeft(path.Clean("/"+hdr.Name), "/")
+		files[name] = memfs.NewFile(name, data, hdr.ModTime)
+	}
+}
@@ -0,0 +1,212 @@
+// Package godef type-checks local project sources, to link
+// identifiers in highlighted source code to their definitions.
+package godef
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gofu/gomon/env"
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/profiler"
+)
+
+// Definition of an identifier.
+type Definition struct {
+	// FileLine where the identifier is defined.
+	profiler.FileLine
+	// Object describes the identifier and its type, eg. "var ch chan int".
+	Object string
+}
+
+// use of an identifier in a project file.
+type use struct {
+	line, col, end int
+	// def is the index of Definition
+	def int
+}
+
+// Index of identifiers used in project source files, and their definitions.
+// It's empty until Load succeeds.
+type Index struct {
+	// Env contains local root paths.
+	Env env.Env
+	// URL returns the URL showing a definition. Required.
+	URL func(profiler.FileLine) string
+	mu  sync.RWMutex
+	// defs contains definitions of used identifiers
+	defs []Definition
+	// files contains uses sorted by position, keyed by project file path
+	files map[string][]use
+}
+
+// Load type-checks project packages in Env.Root and its subdirectories,
+// and indexes identifiers used in them. Dependencies are type-checked
+// from source, as compiled export data depends on the Go version.
+func (x *Index) Load(ctx context.Context) error {
+	root := filepath.Clean(x.Env.Root)
+	module, err := modulePath(root)
+	if err != nil {
+		return err
+	}
+	locations := x.Env.Normalized()
+	ctxt := build.Default
+	ctxt.Dir = root
+	if len(x.Env.GoRoot) != 0 {
+		ctxt.GOROOT = filepath.Clean(x.Env.GoRoot)
+	}
+	imp := &importer{
+		ctxt:     ctxt,
+		module:   module,
+		found:    map[string]*build.Package{},
+		fset:     token.NewFileSet(),
+		sizes:    types.SizesFor("gc", runtime.GOARCH),
+		packages: map[string]*types.Package{},
+	}
+	defs := map[types.Object]int{}
+	var defList []Definition
+	files := map[string][]use{}
+	err = filepath.WalkDir(root, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if dir != root && skipDir(dir, d.Name()) {
+			return filepath.SkipDir
+		}
+		bp, err := ctxt.ImportDir(dir, 0)
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		importPath := path.Join(module, filepath.ToSlash(rel))
+		syntax, err := imp.parse(bp, 0)
+		if err != nil {
+			return err
+		}
+		info := &types.Info{Uses: map[*ast.Ident]types.Object{}}
+		conf := imp.config()
+		pkg, _ := conf.Check(importPath, imp.fset, syntax, info)
+		if _, ok := imp.packages[importPath]; !ok {
+			imp.packages[importPath] = pkg
+		}
+		qualifier := types.RelativeTo(pkg)
+		for id, obj := range info.Uses {
+			if !obj.Pos().IsValid() {
+				// universe scope
+				continue
+			}
+			if _, ok := obj.(*types.PkgName); ok {
+				continue
+			}
+			def, ok := defs[obj]
+			if !ok {
+				pos := imp.fset.Position(obj.Pos())
+				rootType, file, err := locations.FileLocation(filepath.ToSlash(pos.Filename))
+				if err != nil {
+					continue
+				}
+				def = len(defList)
+				defs[obj] = def
+				defList = append(defList, Definition{
+					FileLine: profiler.FileLine{Root: rootType, File: file, Line: pos.Line},
+					Object:   types.ObjectString(obj, qualifier),
+				})
+			}
+			pos := imp.fset.Position(id.Pos())
+			file, err := filepath.Rel(root, pos.Filename)
+			if err != nil {
+				continue
+			}
+			file = filepath.ToSlash(file)
+			files[file] = append(files[file], use{line: pos.Line, col: pos.Column, end: pos.Column + len(id.Name), def: def})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, uses := range files {
+		sort.Slice(uses, func(i, j int) bool {
+			if uses[i].line != uses[j].line {
+				return uses[i].line < uses[j].line
+			}
+			return uses[i].col < uses[j].col
+		})
+	}
+	x.mu.Lock()
+	x.defs, x.files = defList, files
+	x.mu.Unlock()
+	return nil
+}
+
+// Anchors links identifiers on lines first to last of a project file to their definitions.
+func (x *Index) Anchors(file profiler.FileLine, first, last int) []highlight.Anchor {
+	if file.Root != profiler.RootTypeProject {
+		return nil
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	uses := x.files[file.File]
+	i := sort.Search(len(uses), func(i int) bool { return uses[i].line >= first })
+	var anchors []highlight.Anchor
+	for ; i < len(uses) && uses[i].line <= last; i++ {
+		u := uses[i]
+		def := x.defs[u.def]
+		anchors = append(anchors, highlight.Anchor{
+			Line:  u.line,
+			Col:   u.col,
+			End:   u.end,
+			URL:   x.URL(def.FileLine),
+			Title: def.Object,
+		})
+	}
+	return anchors
+}
+
+// skipDir reports whether dir is ignored by the go command, or a nested module.
+func skipDir(dir, name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
+
+// modulePath returns the module path from go.mod in root.
+func modulePath(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(line[len("module "):]), `"`), nil
+		}
+	}
+	return "", errors.New("go.mod: missing module path")
+}
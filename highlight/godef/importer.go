@@ -0,0 +1,118 @@
+package godef
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// importer type-checks imported packages from source, resolving them
+// relative to the build context directory. Function bodies of imported
+// packages are not type-checked, as only their declarations are used.
+type importer struct {
+	ctxt  build.Context
+	fset  *token.FileSet
+	sizes types.Sizes
+	// module path of the project in ctxt.Dir
+	module string
+	// found contains located packages by import path,
+	// as locating module packages runs the go command
+	found map[string]*build.Package
+	// packages contains imported packages by import path,
+	// nil while the package is being imported
+	packages map[string]*types.Package
+}
+
+func (imp *importer) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, imp.ctxt.Dir, 0)
+}
+
+func (imp *importer) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	bp, err := imp.find(path, dir)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := imp.packages[bp.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through package %q", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+	imp.packages[bp.ImportPath] = nil
+	files, err := imp.parse(bp, 0)
+	if err != nil {
+		delete(imp.packages, bp.ImportPath)
+		return nil, err
+	}
+	conf := imp.config()
+	conf.IgnoreFuncBodies = true
+	pkg, _ := conf.Check(bp.ImportPath, imp.fset, files, nil)
+	imp.packages[bp.ImportPath] = pkg
+	return pkg, nil
+}
+
+// find locates a package by import path. Project packages are found
+// in their directory, others are cached, as vendor directories of
+// module dependencies are not supported.
+func (imp *importer) find(path, dir string) (*build.Package, error) {
+	if bp, ok := imp.found[path]; ok {
+		return bp, nil
+	}
+	var bp *build.Package
+	var err error
+	if rel, ok := cutModule(path, imp.module); ok {
+		bp, err = imp.ctxt.ImportDir(filepath.Join(imp.ctxt.Dir, filepath.FromSlash(rel)), 0)
+	} else {
+		bp, err = imp.ctxt.Import(path, dir, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	bp.ImportPath = path
+	imp.found[path] = bp
+	return bp, nil
+}
+
+// cutModule returns path relative to module, if it's within module.
+func cutModule(path, module string) (string, bool) {
+	if path == module {
+		return ".", true
+	}
+	if strings.HasPrefix(path, module+"/") {
+		return path[len(module)+1:], true
+	}
+	return "", false
+}
+
+// config returns type-checking configuration, that ignores type errors,
+// so packages that fail to compile are still partially indexed.
+func (imp *importer) config() types.Config {
+	return types.Config{
+		Importer:    imp,
+		Sizes:       imp.sizes,
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+}
+
+// parse returns parsed files of a package.
+func (imp *importer) parse(bp *build.Package, mode parser.Mode) ([]*ast.File, error) {
+	var files []*ast.File
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		f, err := parser.ParseFile(imp.fset, filepath.Join(bp.Dir, name), nil, mode|parser.SkipObjectResolution)
+		if f != nil {
+			files = append(files, f)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
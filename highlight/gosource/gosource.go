@@ -0,0 +1,209 @@
+// Package gosource parses Go source files, to find functions enclosing source lines.
+package gosource
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gofu/gomon/profiler"
+)
+
+// Func is a function declaration or literal, spanning source lines.
+type Func struct {
+	// Name of a declared function, as printed in stack traces without the
+	// package prefix, eg. "main", "T.String" or "(*T).Close".
+	// Empty for function literals.
+	Name string
+	// Start line of the function, starting from 1.
+	Start int
+	// End line of the function, inclusive.
+	End int
+}
+
+// Contains reports whether line is within function lines.
+func (f Func) Contains(line int) bool {
+	return f.Start <= line && line <= f.End
+}
+
+// File contains parsed information of a single .go source file.
+type File struct {
+	// Package name declared in the file.
+	Package string
+	// Lines is the number of lines in the file.
+	Lines int
+	// Decls are top-level function declarations, ordered by position.
+	Decls []Func
+	// Lits are function literals, ordered by position.
+	Lits []Func
+	// Linknames maps "importpath.name" of //go:linkname
+	// directives to the local function names.
+	Linknames map[string]string
+}
+
+// Parse .go source file data. Name is only used for error messages.
+func Parse(name string, src []byte) (*File, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	file := &File{
+		Package: f.Name.Name,
+		Lines:   bytes.Count(src, []byte("\n")),
+	}
+	if len(src) != 0 && src[len(src)-1] != '\n' {
+		file.Lines++
+	}
+	lines := func(n ast.Node) Func {
+		return Func{Start: fset.Position(n.Pos()).Line, End: fset.Position(n.End()).Line}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			fn := lines(n)
+			fn.Name = declName(n)
+			file.Decls = append(file.Decls, fn)
+		case *ast.FuncLit:
+			file.Lits = append(file.Lits, lines(n))
+		}
+		return true
+	})
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			fields := strings.Fields(c.Text)
+			if len(fields) != 3 || fields[0] != "//go:linkname" {
+				continue
+			}
+			if file.Linknames == nil {
+				file.Linknames = map[string]string{}
+			}
+			file.Linknames[fields[2]] = fields[1]
+		}
+	}
+	return file, nil
+}
+
+// Enclosing returns the function declaration containing line, and the
+// innermost function literal containing line, if any.
+func (f *File) Enclosing(line int) (decl, lit *Func) {
+	for i := range f.Decls {
+		if f.Decls[i].Contains(line) {
+			decl = &f.Decls[i]
+			break
+		}
+	}
+	for i := range f.Lits {
+		if f.Lits[i].Contains(line) {
+			// literals are ordered by position, so the last match is the innermost one
+			lit = &f.Lits[i]
+		}
+	}
+	return decl, lit
+}
+
+// Find returns all declared functions with the provided name.
+func (f *File) Find(name string) []Func {
+	var found []Func
+	for _, fn := range f.Decls {
+		if fn.Name == name {
+			found = append(found, fn)
+		}
+	}
+	return found
+}
+
+// declName returns function name as printed in stack traces.
+func declName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	var ptr bool
+	if star, ok := typ.(*ast.StarExpr); ok {
+		ptr, typ = true, star.X
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	var recv string
+	if ident, ok := typ.(*ast.Ident); ok {
+		recv = ident.Name
+	}
+	if ptr {
+		return "(*" + recv + ")." + fn.Name.Name
+	}
+	return recv + "." + fn.Name.Name
+}
+
+var (
+	// matches generic type parameters, eg. "[...]"
+	typeParamsRegexp = regexp.MustCompile(`\[[^\]]*]`)
+	// matches closure, go/defer wrapper and init suffixes, eg. ".func1", ".func1.2", ".gowrap1", ".0"
+	closureRegexp = regexp.MustCompile(`(?:\.(?:func|gowrap|deferwrap)\d+(?:-range\d+)*|\.\d+)+$`)
+)
+
+// FuncName returns the declared function name of a stack trace method,
+// eg. "(*T).Run.func1" returns "(*T).Run". Closure reports whether method
+// is a function literal. Empty name is returned for package-level closures.
+func FuncName(method string) (name string, closure bool) {
+	method, _ = profiler.CutCreatedIn(method)
+	method = typeParamsRegexp.ReplaceAllString(method, "")
+	name = closureRegexp.ReplaceAllString(method, "")
+	closure = strings.Contains(method[len(name):], ".func")
+	if strings.HasSuffix(name, ".") || name == "init" && closure {
+		// closures of package-level variables, eg. "glob..func1"
+		return "", closure
+	}
+	return name, closure
+}
+
+var (
+	// matches major version import path element, eg. "v2"
+	majorVersionRegexp = regexp.MustCompile(`^v\d+$`)
+	// matches gopkg.in style major version suffix, eg. "yaml.v3"
+	versionSuffixRegexp = regexp.MustCompile(`\.v\d+$`)
+)
+
+// PackageName guesses package name from its import path, as printed in stack traces.
+func PackageName(importPath string) string {
+	if unescaped, err := url.PathUnescape(importPath); err == nil {
+		importPath = unescaped
+	}
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && majorVersionRegexp.MatchString(name) {
+		name = elems[len(elems)-2]
+	}
+	return versionSuffixRegexp.ReplaceAllString(name, "")
+}
+
+// SamePackage reports whether package name declared in source code
+// likely belongs to the import path.
+func SamePackage(importPath, name string) bool {
+	name = strings.TrimSuffix(name, "_test")
+	if name == "main" || importPath == "main" {
+		return name == importPath
+	}
+	normalize := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				return r
+			}
+			if r >= 'A' && r <= 'Z' {
+				return r + 'a' - 'A'
+			}
+			return -1
+		}, s)
+	}
+	pkg, name := normalize(PackageName(importPath)), normalize(name)
+	// eg. "go-yaml" import path with package name "yaml"
+	return strings.HasPrefix(pkg, name) || strings.HasSuffix(pkg, name)
+}
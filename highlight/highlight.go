@@ -3,13 +3,18 @@ package highlight
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"runtime"
 	"strings"
+	"unsafe"
 
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/formatters/html"
 	"github.com/gofu/gomon/profiler"
 	"github.com/gofu/gomon/style"
+	"golang.org/x/sync/errgroup"
 )
 
 // Highlighter provides styled HTML segment of a source code.
@@ -19,98 +24,240 @@ type Highlighter interface {
 	Highlight(profiler.FileLine, Options, *profiler.Highlight) error
 }
 
+// Verifier checks whether local source code matches the running binary.
+type Verifier interface {
+	// Verify returns nil if local source code matches the call stack
+	// frame, otherwise it describes the mismatch.
+	Verify(profiler.CallStack) (*profiler.Mismatch, error)
+}
+
+// NotFound is the Mismatch warning of frames, whose local source file does not exist.
+const NotFound = "local source file not found"
+
 // Options for highlighting a line.
 type Options struct {
 	// WrapSize is the number of lines preceding/succeeding the current line.
 	// Negative number disables highlight.
 	WrapSize int `json:"wrapSize"`
+	// Func highlights the whole innermost function enclosing the current
+	// line, if the highlighter can find it; otherwise WrapSize is used.
+	Func bool `json:"func,omitempty"`
+	// WholeFile highlights the whole file with linkable line numbers (see
+	// LineIDPrefix) in Prefix, marking the current line if it's in range.
+	WholeFile bool `json:"wholeFile,omitempty"`
 }
 
-// Tokens fills *profiler.Highlight with styled HTML of a source code segment.
-func Tokens(allTokens []chroma.Token, line int, opts Options, hl *profiler.Highlight) error {
-	if hl == nil {
-		return fmt.Errorf("cannot highlight nil %T", hl)
-	}
-	wrapSize := opts.WrapSize
-	if wrapSize < 0 {
-		return nil
+// LineIDPrefix prefixes line numbers in HTML IDs of whole highlighted files, eg. L12.
+const LineIDPrefix = "L"
+
+// LineTokens contains source code tokens indexed by line, so segments
+// can be highlighted without walking tokens from the start of the file.
+// Tokens spanning multiple lines, eg. block comments and raw strings,
+// are split at line boundaries, so segments contain only their lines.
+type LineTokens struct {
+	tokens []chroma.Token
+	// starts contains the start position of every line
+	starts []tokenPos
+}
+
+// tokenPos is a position within tokens.
+type tokenPos struct {
+	// index of the token
+	index int
+	// offset within the token value, in bytes
+	offset int
+}
+
+// Size estimates the memory used by l in bytes,
+// including the token values and the line index.
+func (l *LineTokens) Size() int64 {
+	n := int64(cap(l.tokens))*int64(unsafe.Sizeof(chroma.Token{})) +
+		int64(cap(l.starts))*int64(unsafe.Sizeof(tokenPos{}))
+	for _, tok := range l.tokens {
+		n += int64(len(tok.Value))
 	}
-	var tokens []chroma.Token
-	var buf bytes.Buffer
-	base := 1
-	if wrapDiff := line - wrapSize; wrapDiff > 0 {
-		base = wrapDiff
-	}
-	makeFormatter := func(baseLine, mark int) chroma.Formatter {
-		opts := []html.Option{
-			html.WithLineNumbers(true),
-			html.LineNumbersInTable(true),
-			html.Standalone(false),
-			html.BaseLineNumber(baseLine),
-			//html.WrapLongLines(true),
-			html.TabWidth(3),
-		}
-		if mark != 0 {
-			opts = append(opts, html.HighlightLines([][2]int{{mark, mark}}))
-		}
-		return html.New(opts...)
-	}
-	skipLines := line - 1 - wrapSize
-	if skipLines < 0 {
-		skipLines = 0
-	}
-	stopAtLine := line - 1 + wrapSize
-	formatter := makeFormatter(base, line)
-	var gotPrefix bool
-	var haveLines int
-	setSuffix := func() error {
-		if len(tokens) == 0 {
-			return nil
-		}
-		buf.Reset()
-		err := formatter.Format(&buf, style.Vulcan, chroma.Literator(tokens...))
-		if err != nil {
-			return err
-		}
-		hl.Suffix = buf.String()
-		tokens = tokens[:0]
-		return nil
+	return n
+}
+
+// IndexLines indexes tokens by line.
+func IndexLines(tokens []chroma.Token) *LineTokens {
+	l := &LineTokens{tokens: tokens}
+	if len(tokens) == 0 {
+		return l
 	}
-	for _, tok := range allTokens {
-		lfCount := strings.Count(tok.Value, "\n")
-		if haveLines+lfCount < skipLines {
-			haveLines += lfCount
-			continue
-		}
-		if haveLines+lfCount >= line && !gotPrefix {
-			haveLines += lfCount
-			tokens = append(tokens, tok)
-			buf.Reset()
-			err := formatter.Format(&buf, style.Vulcan, chroma.Literator(tokens...))
-			if err != nil {
-				return err
-			}
-			gotPrefix = true
-			tokens = tokens[:0]
-			hl.Prefix = buf.String()
-			if wrapSize <= 0 {
+	l.starts = append(l.starts, tokenPos{})
+	for i, tok := range tokens {
+		for offset := 0; ; {
+			lf := strings.IndexByte(tok.Value[offset:], '\n')
+			if lf == -1 {
 				break
 			}
-			formatter = makeFormatter(line+1, 0)
-			continue
+			// lines starting after a token ending with newline, start
+			// with its empty remainder, same as chroma.SplitTokensIntoLines
+			offset += lf + 1
+			l.starts = append(l.starts, tokenPos{index: i, offset: offset})
 		}
-		if haveLines+lfCount > stopAtLine {
-			tokens = append(tokens, tok)
-			if err := setSuffix(); err != nil {
-				return err
-			}
-			break
+	}
+	// strip empty last line
+	last, n := tokens[len(tokens)-1], len(l.starts)
+	if end := (tokenPos{index: len(tokens) - 1, offset: len(last.Value)}); n > 1 && l.starts[n-1] == end {
+		l.starts = l.starts[:n-1]
+	}
+	return l
+}
+
+// Len returns the number of lines.
+func (l *LineTokens) Len() int {
+	return len(l.starts)
+}
+
+// Lines returns tokens of lines from first to last, inclusive and starting from 1.
+// Tokens spanning multiple lines are split at the first and last line boundaries.
+func (l *LineTokens) Lines(first, last int) []chroma.Token {
+	if first < 1 {
+		first = 1
+	}
+	if last > len(l.starts) {
+		last = len(l.starts)
+	}
+	if first > last {
+		return nil
+	}
+	start := l.starts[first-1]
+	end := tokenPos{index: len(l.tokens) - 1, offset: len(l.tokens[len(l.tokens)-1].Value)}
+	if last < len(l.starts) {
+		end = l.starts[last]
+	}
+	tokens := make([]chroma.Token, 0, end.index-start.index+1)
+	for i := start.index; i <= end.index; i++ {
+		tok := l.tokens[i]
+		if i == end.index {
+			tok.Value = tok.Value[:end.offset]
 		}
-		for i, overflow := 0, skipLines-haveLines; i < overflow; i++ {
-			_, tok.Value, _ = strings.Cut(tok.Value, "\n")
+		if i == start.index {
+			tok.Value = tok.Value[start.offset:]
 		}
 		tokens = append(tokens, tok)
-		haveLines += lfCount
 	}
-	return setSuffix()
+	return tokens
+}
+
+// Range fills *profiler.Highlight with styled HTML of a source code segment.
+// Prefix contains before lines preceding the current line and the current
+// line itself, while Suffix contains after lines succeeding it.
+// Tokens matching anchors are linked.
+func (l *LineTokens) Range(line, before, after int, anchors []Anchor, hl *profiler.Highlight) error {
+	return l.formatRange(line, before, after, hl, func(tokens []chroma.Token, baseLine, mark int) (string, error) {
+		return format(tokens, baseLine, mark, anchors)
+	})
+}
+
+// formatRange fills *profiler.Highlight with a source code segment, formatted by fn.
+// If mark!=0, fn should mark that line number.
+func (l *LineTokens) formatRange(line, before, after int, hl *profiler.Highlight, fn func(tokens []chroma.Token, baseLine, mark int) (string, error)) error {
+	if hl == nil {
+		return fmt.Errorf("cannot highlight nil %T", hl)
+	}
+	if before < 0 || after < 0 || line < 1 || line > l.Len() {
+		return nil
+	}
+	first := line - before
+	if first < 1 {
+		first = 1
+	}
+	var err error
+	hl.Prefix, err = fn(l.Lines(first, line), first, line)
+	if err != nil {
+		return err
+	}
+	suffix := l.Lines(line+1, line+after)
+	if len(suffix) != 0 && len(suffix[0].Value) == 0 {
+		// empty remainder of the last prefix token
+		suffix = suffix[1:]
+	}
+	hl.Suffix, err = fn(suffix, line+1, 0)
+	return err
+}
+
+// File fills hl.Prefix with styled HTML of all lines, with linkable line
+// numbers, marking line if it's in range. Tokens matching anchors are linked.
+func (l *LineTokens) File(line int, anchors []Anchor, hl *profiler.Highlight) error {
+	if hl == nil {
+		return fmt.Errorf("cannot highlight nil %T", hl)
+	}
+	if line < 1 || line > l.Len() {
+		line = 0
+	}
+	var err error
+	hl.Prefix, err = format(l.Lines(1, l.Len()), 1, line, anchors, html.LinkableLineNumbers(true, LineIDPrefix))
+	hl.Suffix = ""
+	return err
+}
+
+// format returns styled HTML of tokens, numbered from baseLine.
+// If mark!=0, that line number is marked.
+// Tokens are styled with CSS classes, see WriteCSS.
+func format(tokens []chroma.Token, baseLine, mark int, anchors []Anchor, extra ...html.Option) (string, error) {
+	if len(tokens) == 0 {
+		return "", nil
+	}
+	opts := append(formatOptions(), html.BaseLineNumber(baseLine))
+	opts = append(opts, extra...)
+	if mark != 0 {
+		opts = append(opts, html.HighlightLines([][2]int{{mark, mark}}))
+	}
+	var buf bytes.Buffer
+	err := html.New(opts...).Format(&buf, style.Vulcan, chroma.Literator(markAnchors(tokens, baseLine, anchors)...))
+	return linkAnchors(buf.String(), anchors), err
+}
+
+// WriteCSS writes the stylesheet of theme s for highlighted HTML.
+func WriteCSS(w io.Writer, s *chroma.Style) error {
+	err := html.New(formatOptions()...).WriteCSS(w, s)
+	if err != nil {
+		return err
+	}
+	// anchors keep token colors
+	_, err = io.WriteString(w, "/* Anchor */ .chroma a { color: inherit; text-decoration: none }\n"+
+		"/* Anchor */ .chroma a:hover { text-decoration: underline }\n")
+	return err
+}
+
+// formatOptions returns options shared by the HTML formatter and stylesheet.
+func formatOptions() []html.Option {
+	return []html.Option{
+		html.WithClasses(true),
+		html.WithLineNumbers(true),
+		html.LineNumbersInTable(true),
+		html.Standalone(false),
+		//html.WrapLongLines(true),
+		html.TabWidth(3),
+	}
+}
+
+// VerifyGoroutines sets profiler.CallStack.Mismatch on frames of
+// goroutines that do not match local source, in parallel.
+// Frames at the same file/line are verified only once, see VerifyMemo.
+func VerifyGoroutines(ctx context.Context, goroutines []profiler.Goroutine, verifier Verifier) error {
+	verifier = NewVerifyMemo(verifier)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.NumCPU())
+	for _, gr := range goroutines {
+		gr := gr
+		g.Go(func() error {
+			for j := range gr.CallStack {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				s := &gr.CallStack[j]
+				mismatch, err := verifier.Verify(*s)
+				if err != nil {
+					return err
+				}
+				s.Mismatch = mismatch
+			}
+			return nil
+		})
+	}
+	return g.Wait()
 }
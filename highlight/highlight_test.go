@@ -0,0 +1,163 @@
+package highlight
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers/g"
+	"github.com/gofu/gomon/profiler"
+)
+
+// testSource has a block comment and a raw string, that Go lexer
+// tokenizes as single tokens spanning multiple lines.
+const testSource = "package p\n" +
+	"\n" +
+	"/* block\n" +
+	"comment */\n" +
+	"var s = `raw\n" +
+	"string\n" +
+	"lines`\n" +
+	"\n" +
+	"func f() {\n" +
+	"\t// x\n" +
+	"\treturn\n" +
+	"}\n"
+
+// segment is the text of a highlighted segment and its first line number.
+type segment struct {
+	text string
+	base int
+}
+
+// legacyRange returns segments of line, selected the same way as the
+// removed highlight.Tokens, which walked tokens from the start of the
+// file. Tokens spanning multiple lines were not split, except at the
+// start of the prefix if they were not its first token, so segments
+// could contain lines out of range.
+func legacyRange(allTokens []chroma.Token, line, before, after int) (prefix, suffix segment) {
+	var tokens []chroma.Token
+	text := func() string {
+		var sb strings.Builder
+		for _, tok := range tokens {
+			sb.WriteString(tok.Value)
+		}
+		tokens = tokens[:0]
+		return sb.String()
+	}
+	base := 1
+	if wrapDiff := line - before; wrapDiff > 0 {
+		base = wrapDiff
+	}
+	prefix.base = base
+	suffix.base = line + 1
+	skipLines := line - 1 - before
+	if skipLines < 0 {
+		skipLines = 0
+	}
+	stopAtLine := line - 1 + after
+	var gotPrefix bool
+	var haveLines int
+	for _, tok := range allTokens {
+		lfCount := strings.Count(tok.Value, "\n")
+		if haveLines+lfCount < skipLines {
+			haveLines += lfCount
+			continue
+		}
+		if haveLines+lfCount >= line && !gotPrefix {
+			haveLines += lfCount
+			tokens = append(tokens, tok)
+			gotPrefix = true
+			prefix.text = text()
+			if after <= 0 {
+				break
+			}
+			continue
+		}
+		if haveLines+lfCount > stopAtLine {
+			tokens = append(tokens, tok)
+			break
+		}
+		for i, overflow := 0, skipLines-haveLines; i < overflow; i++ {
+			_, tok.Value, _ = strings.Cut(tok.Value, "\n")
+		}
+		tokens = append(tokens, tok)
+		haveLines += lfCount
+	}
+	suffix.text = text()
+	return prefix, suffix
+}
+
+// lineRange returns segments of line, selected by LineTokens.Range.
+func lineRange(l *LineTokens, line, before, after int) (prefix, suffix segment, err error) {
+	var segments []segment
+	err = l.formatRange(line, before, after, &profiler.Highlight{}, func(tokens []chroma.Token, baseLine, mark int) (string, error) {
+		var sb strings.Builder
+		for _, tok := range tokens {
+			sb.WriteString(tok.Value)
+		}
+		segments = append(segments, segment{text: sb.String(), base: baseLine})
+		return sb.String(), nil
+	})
+	if len(segments) != 2 {
+		return prefix, suffix, fmt.Errorf("formatted %d segments, want 2", len(segments))
+	}
+	return segments[0], segments[1], err
+}
+
+func TestLineTokensRange(t *testing.T) {
+	iter, err := g.Go.Tokenise(nil, testSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allTokens := iter.Tokens()
+	lines := IndexLines(allTokens)
+	if got, want := lines.Len(), 12; got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+	tests := []struct {
+		name                string
+		line, before, after int
+		prefix, suffix      string
+		// legacyDiffers marks cases where the former Tokens kept multi-line
+		// tokens whole, so its segments contained lines out of range.
+		legacyDiffers bool
+	}{
+		{name: "single line tokens", line: 10, before: 1, after: 1, prefix: "func f() {\n\t// x\n", suffix: "\treturn\n"},
+		{name: "first line", line: 1, before: 5, after: 1, prefix: "package p\n", suffix: "\n"},
+		{name: "last line", line: 12, before: 1, after: 3, prefix: "\treturn\n}\n"},
+		{name: "current line only", line: 9, prefix: "func f() {\n"},
+		{name: "comment end", line: 4, prefix: "comment */\n"},
+		{name: "comment start", line: 3, after: 1, prefix: "/* block\n", suffix: "comment */\n", legacyDiffers: true},
+		{name: "comment in prefix", line: 5, before: 1, prefix: "comment */\nvar s = `raw\n", legacyDiffers: true},
+		{name: "raw string start", line: 5, after: 1, prefix: "var s = `raw\n", suffix: "string\n", legacyDiffers: true},
+		{name: "raw string middle", line: 6, prefix: "string\n", legacyDiffers: true},
+		{name: "raw string end", line: 7, before: 1, after: 1, prefix: "string\nlines`\n", suffix: "\n"},
+		{name: "raw string in suffix", line: 4, after: 2, prefix: "comment */\n", suffix: "var s = `raw\nstring\n", legacyDiffers: true},
+		{name: "raw string whole", line: 5, after: 2, prefix: "var s = `raw\n", suffix: "string\nlines`\n", legacyDiffers: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, suffix, err := lineRange(lines, tt.line, tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first := tt.line - tt.before
+			if first < 1 {
+				first = 1
+			}
+			if prefix.text != tt.prefix || prefix.base != first {
+				t.Errorf("prefix = %q from line %d, want %q from line %d", prefix.text, prefix.base, tt.prefix, first)
+			}
+			if suffix.text != tt.suffix || (len(suffix.text) != 0 && suffix.base != tt.line+1) {
+				t.Errorf("suffix = %q from line %d, want %q from line %d", suffix.text, suffix.base, tt.suffix, tt.line+1)
+			}
+			legacyPrefix, legacySuffix := legacyRange(allTokens, tt.line, tt.before, tt.after)
+			same := legacyPrefix == prefix && legacySuffix.text == suffix.text
+			if same == tt.legacyDiffers {
+				t.Errorf("legacy prefix %q, suffix %q; legacyDiffers = %v", legacyPrefix.text, legacySuffix.text, tt.legacyDiffers)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package highlightfs
+
+import (
+	"container/list"
+	"time"
+)
+
+// DefaultCacheSize is the default FS.CacheSize.
+const DefaultCacheSize = 64 << 20
+
+// validateInterval is the minimum interval between checking
+// whether a cached file was modified on the filesystem.
+const validateInterval = 2 * time.Second
+
+// CacheStats contains statistics of cached source files.
+type CacheStats struct {
+	// Files is the number of cached files.
+	Files int `json:"files"`
+	// Size is the estimated total size of cached files, their tokens
+	// and highlighted segments in bytes.
+	Size int64 `json:"size"`
+	// MaxSize is the maximum total size of cached files in bytes.
+	MaxSize int64 `json:"maxSize"`
+	// Hits is the number of files served from cache.
+	Hits uint64 `json:"hits"`
+	// Misses is the number of files read from the filesystem.
+	Misses uint64 `json:"misses"`
+	// Evictions is the number of files removed, to stay within MaxSize.
+	Evictions uint64 `json:"evictions"`
+	// Invalidations is the number of files removed, because they were modified.
+	Invalidations uint64 `json:"invalidations"`
+}
+
+// cache is a size-bounded LRU of source files, keyed by path.
+// It's not safe for concurrent use.
+type cache struct {
+	files map[string]*list.Element
+	// lru contains *file elements, most recently used first.
+	lru   list.List
+	stats CacheStats
+}
+
+// get returns cached file, and marks it as recently used.
+func (c *cache) get(name string) (*file, bool) {
+	elem, ok := c.files[name]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*file), true
+}
+
+// put adds file to cache, evicting least recently used
+// files while the total size is over maxSize.
+func (c *cache) put(f *file, maxSize int64) {
+	if c.files == nil {
+		c.files = map[string]*list.Element{}
+	}
+	c.remove(f.name)
+	c.files[f.name] = c.lru.PushFront(f)
+	c.stats.Size += f.cost
+	c.evict(maxSize)
+}
+
+// grow increases the cost of a cached file by n bytes,
+// evicting least recently used files if needed.
+func (c *cache) grow(f *file, n, maxSize int64) {
+	elem, ok := c.files[f.name]
+	if !ok || elem.Value.(*file) != f {
+		return
+	}
+	f.cost += n
+	c.stats.Size += n
+	c.evict(maxSize)
+}
+
+// evict least recently used files, while the total size is over maxSize.
+func (c *cache) evict(maxSize int64) {
+	c.stats.MaxSize = maxSize
+	for c.stats.Size > maxSize && c.lru.Len() > 1 {
+		c.remove(c.lru.Back().Value.(*file).name)
+		c.stats.Evictions++
+	}
+}
+
+// remove file from cache, if it's cached.
+func (c *cache) remove(name string) {
+	elem, ok := c.files[name]
+	if !ok {
+		return
+	}
+	c.lru.Remove(elem)
+	delete(c.files, name)
+	c.stats.Size -= elem.Value.(*file).cost
+}
+
+// flush removes all cached files, keeping statistics.
+func (c *cache) flush() {
+	c.files = nil
+	c.lru.Init()
+	c.stats.Size = 0
+}
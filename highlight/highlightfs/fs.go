@@ -6,88 +6,279 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"sync"
+	"time"
+	"unsafe"
 
-	"github.com/alecthomas/chroma"
-	"github.com/alecthomas/chroma/lexers/g"
 	"github.com/gofu/gomon/env"
 	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/highlight/gosource"
 	"github.com/gofu/gomon/profiler"
 	"golang.org/x/sync/singleflight"
 )
 
 // FS uses single-flight to lock filesystem reading/highlighting
 // from multiple goroutines, and caches highlighted file source.
+// Cached files are reloaded when their size or modification time changes.
 type FS struct {
-	FS    fs.FS
-	Env   env.Env
-	mu    sync.RWMutex
-	sf    singleflight.Group
-	cache map[string][]chroma.Token
+	// FS to read source files from. If nil, the local filesystem is used.
+	// Absolute paths, eg. of cgo generated files, are always read locally.
+	FS fs.FS
+	// Env contains root paths, either absolute local paths,
+	// or paths within FS if it's set (see RootEnv).
+	Env env.Env
+	// CacheSize is the maximum total size of cached files in bytes, including
+	// their tokens and highlighted segments.
+	// If 0, DefaultCacheSize is used.
+	CacheSize int64
+	// Anchors optionally links identifiers in highlighted source code.
+	// Flush cached files if anchors change.
+	Anchors highlight.Anchors
+	mu      sync.Mutex
+	sf      singleflight.Group
+	cache   cache
+}
+
+// file is a cached source file.
+type file struct {
+	name    string
+	data    []byte
+	lines   *highlight.LineTokens
+	size    int64
+	modTime time.Time
+	// checked is the last time size/modTime were validated
+	checked time.Time
+	// cost is the estimated memory of data, tokens and rendered segments
+	// in bytes; guarded by FS.mu
+	cost int64
+	// rendered memoizes highlighted segments, until the file is evicted
+	renderMu sync.Mutex
+	rendered map[renderKey]profiler.Highlight
+	// parsed lazily, only for .go files that are verified
+	parseOnce sync.Once
+	parsed    *gosource.File
+	parseErr  error
+}
+
+// renderKey identifies a highlighted segment of a file.
+type renderKey struct {
+	line int
+	opts highlight.Options
+}
+
+// parse returns parsed .go source file, parsing it on first call.
+func (f *file) parse() (*gosource.File, error) {
+	f.parseOnce.Do(func() {
+		f.parsed, f.parseErr = gosource.Parse(f.name, f.data)
+	})
+	return f.parsed, f.parseErr
+}
+
+// enclosingFunc returns the innermost function literal or declaration
+// enclosing line, or nil if it's not found or f is not a .go file.
+func (f *file) enclosingFunc(line int) *gosource.Func {
+	if path.Ext(f.name) != ".go" {
+		return nil
+	}
+	parsed, err := f.parse()
+	if err != nil {
+		return nil
+	}
+	decl, lit := parsed.Enclosing(line)
+	if lit != nil {
+		return lit
+	}
+	return decl
+}
+
+// segment returns the number of lines highlighted before/after line.
+func (f *file) segment(line int, opts highlight.Options) (before, after int) {
+	if opts.WholeFile {
+		return line - 1, f.lines.Len() - line
+	}
+	if opts.Func {
+		if fn := f.enclosingFunc(line); fn != nil {
+			return line - fn.Start, fn.End - line
+		}
+	}
+	return opts.WrapSize, opts.WrapSize
 }
 
 // Highlight source file/line with HTML. If wrapSize<0, no HTML is returned.
 // If wrapSize==0, then only the current line is highlighted, meaning the
 // suffix is empty. If wrapSize>0, then prefix contains 1+wrapSize lines,
-// while suffix contains wrapSize lines.
+// while suffix contains wrapSize lines. If opts.WholeFile, prefix contains
+// the whole file.
 func (h *FS) Highlight(file profiler.FileLine, opts highlight.Options, hl *profiler.Highlight) error {
-	if opts.WrapSize < 0 {
+	if (opts.WrapSize < 0 && !opts.WholeFile) || len(file.File) == 0 {
+		return nil
+	}
+	f, err := h.getFile(h.path(file))
+	if err != nil {
+		return err
+	}
+	key := renderKey{line: file.Line, opts: opts}
+	f.renderMu.Lock()
+	rendered, ok := f.rendered[key]
+	f.renderMu.Unlock()
+	if ok {
+		*hl = rendered
 		return nil
 	}
-	allTokens, err := h.getTokens(path.Join(h.Env.RootPath(file.Root), file.File))
+	before, after := f.segment(file.Line, opts)
+	var anchors []highlight.Anchor
+	if h.Anchors != nil {
+		anchors = h.Anchors.Anchors(file, file.Line-before, file.Line+after)
+	}
+	if opts.WholeFile {
+		err = f.lines.File(file.Line, anchors, &rendered)
+	} else {
+		err = f.lines.Range(file.Line, before, after, anchors, &rendered)
+	}
 	if err != nil {
 		return err
 	}
-	return highlight.Tokens(allTokens, file.Line, opts, hl)
+	f.renderMu.Lock()
+	if f.rendered == nil {
+		f.rendered = map[renderKey]profiler.Highlight{}
+	}
+	f.rendered[key] = rendered
+	f.renderMu.Unlock()
+	h.mu.Lock()
+	h.cache.grow(f, renderedSize(key, rendered), h.maxSize())
+	h.mu.Unlock()
+	*hl = rendered
+	return nil
 }
 
-// getTokens returns parsed tokens from source code file,
-// relying on cache and single-flight.
-func (h *FS) getTokens(file string) ([]chroma.Token, error) {
-	h.mu.RLock()
-	cached, ok := h.cache[file]
-	h.mu.RUnlock()
-	if ok {
+// renderedSize estimates the memory of a memoized segment in bytes.
+func renderedSize(key renderKey, hl profiler.Highlight) int64 {
+	return int64(unsafe.Sizeof(key)+unsafe.Sizeof(hl)) + int64(len(hl.Prefix)+len(hl.Suffix))
+}
+
+// path returns the file path within FS. Paths of cgo generated
+// files are absolute, since they are not in any root.
+func (h *FS) path(file profiler.FileLine) string {
+	return path.Join(h.Env.RootPath(file.Root), file.File)
+}
+
+// Stats returns cache statistics.
+func (h *FS) Stats() CacheStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.cache.stats
+	stats.Files = h.cache.lru.Len()
+	stats.MaxSize = h.maxSize()
+	return stats
+}
+
+// Flush removes all cached files.
+func (h *FS) Flush() {
+	h.mu.Lock()
+	h.cache.flush()
+	h.mu.Unlock()
+}
+
+// maxSize returns the configured cache size.
+func (h *FS) maxSize() int64 {
+	if h.CacheSize == 0 {
+		return DefaultCacheSize
+	}
+	return h.CacheSize
+}
+
+// getFile returns source code file with parsed tokens, relying on
+// cache and single-flight. Cached files are validated at most once
+// per validateInterval.
+func (h *FS) getFile(name string) (*file, error) {
+	h.mu.Lock()
+	cached, ok := h.cache.get(name)
+	if ok && time.Since(cached.checked) < validateInterval {
+		h.cache.stats.Hits++
+		h.mu.Unlock()
 		return cached, nil
 	}
-	v, err, _ := h.sf.Do(file, func() (any, error) {
-		h.mu.RLock()
-		cached, ok := h.cache[file]
+	h.mu.Unlock()
+	v, err, _ := h.sf.Do(name, func() (any, error) {
+		h.mu.Lock()
+		cached, ok := h.cache.get(name)
 		readFS := h.FS
-		h.mu.RUnlock()
+		h.mu.Unlock()
+		if ok && time.Since(cached.checked) >= validateInterval {
+			info, err := statFSFile(readFS, name)
+			h.mu.Lock()
+			if err == nil && info.Size() == cached.size && info.ModTime().Equal(cached.modTime) {
+				cached.checked = time.Now()
+			} else {
+				h.cache.remove(name)
+				h.cache.stats.Invalidations++
+				ok = false
+			}
+			h.mu.Unlock()
+		}
 		if ok {
+			h.mu.Lock()
+			h.cache.stats.Hits++
+			h.mu.Unlock()
 			return cached, nil
 		}
-		data, err := readFSFile(readFS, file)
+		checked := time.Now()
+		data, info, err := readFSFile(readFS, name)
 		if err != nil {
 			return nil, err
 		}
-		iter, err := g.Go.Tokenise(nil, string(data))
+		iter, err := highlight.Lexer(name).Tokenise(nil, string(data))
 		if err != nil {
 			return nil, err
 		}
-		tokens := iter.Tokens()
-		h.mu.Lock()
-		if h.cache == nil {
-			h.cache = map[string][]chroma.Token{}
+		lines := highlight.IndexLines(iter.Tokens())
+		f := &file{
+			name:    name,
+			data:    data,
+			lines:   lines,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			checked: checked,
+			cost:    int64(len(data)) + lines.Size(),
 		}
-		h.cache[file] = tokens
+		h.mu.Lock()
+		h.cache.stats.Misses++
+		h.cache.put(f, h.maxSize())
 		h.mu.Unlock()
-		return tokens, nil
+		return f, nil
 	})
-	cached, _ = v.([]chroma.Token)
+	cached, _ = v.(*file)
 	return cached, err
 }
 
-// readFSFile reads file content from readFS, or local filesystem if readFS==nil.
-func readFSFile(readFS fs.FS, file string) ([]byte, error) {
-	if readFS == nil {
-		return os.ReadFile(file)
+// readFSFile reads file content and info from readFS, or local filesystem
+// if readFS==nil or file is absolute.
+func readFSFile(readFS fs.FS, file string) ([]byte, fs.FileInfo, error) {
+	var f fs.File
+	var err error
+	if readFS == nil || filepath.IsAbs(file) {
+		f, err = os.Open(file)
+	} else {
+		f, err = readFS.Open(file)
 	}
-	f, err := readFS.Open(file)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() { _ = f.Close() }()
-	return io.ReadAll(f)
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(f)
+	return data, info, err
+}
+
+// statFSFile returns file info from readFS, or local filesystem if readFS==nil
+// or file is absolute.
+func statFSFile(readFS fs.FS, file string) (fs.FileInfo, error) {
+	if readFS == nil || filepath.IsAbs(file) {
+		return os.Stat(file)
+	}
+	return fs.Stat(readFS, file)
 }
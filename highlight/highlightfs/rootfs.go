@@ -0,0 +1,81 @@
+package highlightfs
+
+import (
+	"io/fs"
+	"strings"
+
+	"github.com/gofu/gomon/env"
+	"github.com/gofu/gomon/profiler"
+)
+
+// RootEnv is the FS.Env to use along with RootFS. Each root path
+// is the name of its profiler.RootType, eg. "GOROOT/src/fmt/print.go".
+var RootEnv = env.Env{
+	Root:   string(profiler.RootTypeProject),
+	GoRoot: string(profiler.RootTypeGoRoot),
+	GoPath: string(profiler.RootTypeGoPath),
+}
+
+// RootFS serves each source code root from a separate fs.FS.
+// The first element of a name is the root type, the rest is
+// the path relative to that root.
+type RootFS map[profiler.RootType]fs.FS
+
+func (r RootFS) Open(name string) (fs.File, error) {
+	rootFS, file, err := r.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return rootFS.Open(file)
+}
+
+func (r RootFS) Stat(name string) (fs.FileInfo, error) {
+	rootFS, file, err := r.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(rootFS, file)
+}
+
+// lookup returns root filesystem of name, and file path relative to it.
+func (r RootFS) lookup(op, name string) (fs.FS, string, error) {
+	if !fs.ValidPath(name) {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	root, file, _ := strings.Cut(name, "/")
+	rootFS, ok := r[profiler.RootType(root)]
+	if !ok || rootFS == nil {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if len(file) == 0 {
+		file = "."
+	}
+	return rootFS, file, nil
+}
+
+// FallbackFS opens files from the first fs.FS that contains them.
+type FallbackFS []fs.FS
+
+func (f FallbackFS) Open(name string) (fs.File, error) {
+	err := error(&fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist})
+	for _, fsys := range f {
+		var file fs.File
+		file, err = fsys.Open(name)
+		if err == nil {
+			return file, nil
+		}
+	}
+	return nil, err
+}
+
+func (f FallbackFS) Stat(name string) (fs.FileInfo, error) {
+	err := error(&fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist})
+	for _, fsys := range f {
+		var info fs.FileInfo
+		info, err = fs.Stat(fsys, name)
+		if err == nil {
+			return info, nil
+		}
+	}
+	return nil, err
+}
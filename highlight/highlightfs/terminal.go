@@ -0,0 +1,29 @@
+package highlightfs
+
+import (
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/profiler"
+)
+
+// Terminal highlights source code with ANSI escape sequences for
+// terminals, sharing cached source files and tokens of FS.
+// Unlike FS, highlighted segments are not cached.
+type Terminal struct {
+	// FS to read cached source files from.
+	FS *FS
+	// Format of highlighted segments.
+	Format highlight.Terminal
+}
+
+// Highlight fills *profiler.Highlight with terminal output, see (*FS).Highlight.
+func (t Terminal) Highlight(file profiler.FileLine, opts highlight.Options, hl *profiler.Highlight) error {
+	if (opts.WrapSize < 0 && !opts.WholeFile) || len(file.File) == 0 {
+		return nil
+	}
+	f, err := t.FS.getFile(t.FS.path(file))
+	if err != nil {
+		return err
+	}
+	before, after := f.segment(file.Line, opts)
+	return t.Format.Range(f.lines, file.Line, before, after, hl)
+}
@@ -0,0 +1,91 @@
+package highlightfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/highlight/gosource"
+	"github.com/gofu/gomon/profiler"
+)
+
+// maxOffset is the maximum distance in lines, to look for the
+// expected function when local source does not match a frame.
+const maxOffset = 500
+
+// Verify that the call stack frame line is within the expected function
+// in local source code. Only .go files are verified.
+func (h *FS) Verify(stack profiler.CallStack) (*profiler.Mismatch, error) {
+	if stack.Root == profiler.RootTypeCGo || !strings.HasSuffix(stack.File, ".go") {
+		return nil, nil
+	}
+	f, err := h.getFile(h.path(stack.FileLine))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &profiler.Mismatch{Warning: highlight.NotFound}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	parsed, err := f.parse()
+	if err != nil {
+		return &profiler.Mismatch{Warning: fmt.Sprintf("cannot parse local source: %s", err)}, nil
+	}
+	if stack.Line < 1 || stack.Line > parsed.Lines {
+		return &profiler.Mismatch{
+			Warning: fmt.Sprintf("line %d does not exist, local file has %d lines", stack.Line, parsed.Lines),
+		}, nil
+	}
+	name, closure := gosource.FuncName(stack.Method)
+	if !gosource.SamePackage(stack.Package, parsed.Package) {
+		if local, ok := parsed.Linknames[stack.Package+"."+name]; ok {
+			// function is declared in another package, eg. sync.runtime_Semacquire in runtime
+			name = local
+		} else {
+			return &profiler.Mismatch{
+				Warning: fmt.Sprintf("local file declares package %s, expected %s", parsed.Package, stack.Package),
+			}, nil
+		}
+	}
+	if len(name) == 0 {
+		return nil, nil
+	}
+	decl, lit := parsed.Enclosing(stack.Line)
+	switch {
+	case decl == nil:
+		return mismatch(parsed, name, stack.Line, fmt.Sprintf("line %d is outside of any function, expected %s", stack.Line, name)), nil
+	case decl.Name != name:
+		return mismatch(parsed, name, stack.Line, fmt.Sprintf("line %d is in %s, expected %s", stack.Line, decl.Name, name)), nil
+	case closure && lit == nil:
+		return &profiler.Mismatch{
+			Warning: fmt.Sprintf("line %d is not in a function literal of %s", stack.Line, name),
+		}, nil
+	}
+	return nil, nil
+}
+
+// mismatch returns a Mismatch with warning, suggesting the offset to the
+// nearest function declaration named name, if it's found close to line.
+func mismatch(parsed *gosource.File, name string, line int, warning string) *profiler.Mismatch {
+	m := &profiler.Mismatch{Warning: warning}
+	for _, fn := range parsed.Find(name) {
+		offset := fn.Start - line
+		if line > fn.End {
+			offset = fn.End - line
+		}
+		if abs(offset) > maxOffset {
+			continue
+		}
+		if m.Offset == 0 || abs(offset) < abs(m.Offset) {
+			m.Offset = offset
+		}
+	}
+	return m
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
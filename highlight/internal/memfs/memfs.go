@@ -0,0 +1,72 @@
+// Package memfs provides an in-memory read-only fs.FS, containing regular files only.
+package memfs
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// FS maps valid fs.FS paths to files.
+type FS map[string]*File
+
+func (m FS) Open(name string) (fs.File, error) {
+	f, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.Open(), nil
+}
+
+func (m FS) ReadFile(name string) ([]byte, error) {
+	f, err := m.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+func (m FS) lookup(op, name string) (*File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
+
+// File is a regular file stored in memory.
+type File struct {
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+// NewFile returns a file with base name of the provided path.
+func NewFile(name string, data []byte, modTime time.Time) *File {
+	return &File{name: path.Base(name), data: data, modTime: modTime}
+}
+
+// Open returns the file opened for reading.
+func (f *File) Open() fs.File {
+	return &openFile{info: f, Reader: bytes.NewReader(f.data)}
+}
+
+func (f *File) Name() string       { return f.name }
+func (f *File) Size() int64        { return int64(len(f.data)) }
+func (f *File) Mode() fs.FileMode  { return 0444 }
+func (f *File) ModTime() time.Time { return f.modTime }
+func (f *File) IsDir() bool        { return false }
+func (f *File) Sys() any           { return nil }
+
+// openFile is an opened File.
+type openFile struct {
+	info *File
+	*bytes.Reader
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openFile) Close() error               { return nil }
@@ -0,0 +1,87 @@
+package highlight
+
+import (
+	"path"
+	"sync"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/lexers/c"
+	"github.com/alecthomas/chroma/lexers/g"
+)
+
+// lexerCache maps base names of files, with extensions missing
+// from extLexers, to lexers matched by chroma.
+var lexerCache sync.Map
+
+// extLexers are lexers of common call stack file extensions; runtime and crypto
+// frames are often in Go assembly files, while cgo frames are in C/C++ files.
+var extLexers = map[string]chroma.Lexer{
+	".go":  g.Go,
+	".s":   GoAsm,
+	".S":   GoAsm,
+	".c":   c.C,
+	".h":   c.C,
+	".cc":  c.CPP,
+	".cpp": c.CPP,
+	".cxx": c.CPP,
+	".hh":  c.CPP,
+	".hpp": c.CPP,
+	".hxx": c.CPP,
+}
+
+// Lexer returns a lexer for the source file, by its extension or name.
+// Files of unknown type are lexed as plain text.
+func Lexer(file string) chroma.Lexer {
+	if lexer, ok := extLexers[path.Ext(file)]; ok {
+		return lexer
+	}
+	// chroma also matches whole names, eg. "Makefile"
+	base := path.Base(file)
+	if cached, ok := lexerCache.Load(base); ok {
+		return cached.(chroma.Lexer)
+	}
+	lexer := lexers.Match(base)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexerCache.Store(base, lexer)
+	return lexer
+}
+
+// GoAsm lexes Go assembler source files, see https://go.dev/doc/asm.
+var GoAsm = chroma.MustNewLazyLexer(
+	&chroma.Config{
+		Name:      "Go assembly",
+		Aliases:   []string{"goasm"},
+		Filenames: []string{"*.s"},
+		EnsureNL:  true,
+	},
+	goAsmRules,
+)
+
+func goAsmRules() chroma.Rules {
+	return chroma.Rules{
+		"root": {
+			{Pattern: `\n`, Type: chroma.Text},
+			{Pattern: `[^\S\n]+`, Type: chroma.TextWhitespace},
+			{Pattern: `//[^\n]*`, Type: chroma.CommentSingle},
+			{Pattern: `/\*[\s\S]*?\*/`, Type: chroma.CommentMultiline},
+			{Pattern: `#[^\n]*(?:\\\n[^\n]*)*`, Type: chroma.CommentPreproc},
+			{Pattern: `"(?:\\.|[^"\\\n])*"`, Type: chroma.LiteralString},
+			{Pattern: `'(?:\\.|[^'\\\n])'`, Type: chroma.LiteralStringChar},
+			{Pattern: `\b(?:TEXT|DATA|GLOBL|FUNCDATA|PCDATA|PCALIGN)\b`, Type: chroma.KeywordDeclaration},
+			{Pattern: `\b(?:NOSPLIT|NOFRAME|WRAPPER|NEEDCTXT|RODATA|NOPTR|DUPOK|TOPFRAME|TLSBSS|REFLECTMETHOD|ABIWRAPPER|NOPROF)\b`, Type: chroma.NameConstant},
+			{Pattern: `[·∕\w.]*[·∕][\w·∕.]*(?:<>)?|[A-Za-z_][\w·∕.]*(?:<>)?(?=(?:[+-]\d+)?\(SB\))`, Type: chroma.NameFunction},
+			{Pattern: `\b(?:SB|FP|SP|PC)\b`, Type: chroma.NameBuiltin},
+			{Pattern: `\b(?:[RFXYZKVW]\d{1,2}|[ABCD][XLH]|[SD]IL?|BP|g|LR|ZR|RSP|CTR)\b`, Type: chroma.NameVariable},
+			{Pattern: `[A-Za-z_]\w*:`, Type: chroma.NameLabel},
+			{Pattern: `\$?-?(?:0[xX][0-9a-fA-F]+|\d+(?:\.\d+)?(?:[eE][+-]?\d+)?)`, Type: chroma.LiteralNumber},
+			{Pattern: `\b[A-Z][A-Z0-9_.]*\b`, Type: chroma.Keyword},
+			{Pattern: `[A-Za-z_][\w]*`, Type: chroma.Name},
+			{Pattern: `[-+*/%&|^~<>!=]+`, Type: chroma.Operator},
+			{Pattern: `[$,;()\[\]{}:]`, Type: chroma.Punctuation},
+			{Pattern: `.`, Type: chroma.Text},
+		},
+	}
+}
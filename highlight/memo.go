@@ -0,0 +1,95 @@
+package highlight
+
+import (
+	"sync"
+
+	"github.com/gofu/gomon/profiler"
+)
+
+// memoKey identifies a highlighted source code segment.
+type memoKey struct {
+	profiler.FileLine
+	Options
+}
+
+// memoEntry is a single memoized highlight result.
+type memoEntry struct {
+	once sync.Once
+	hl   profiler.Highlight
+	err  error
+}
+
+// Memo is a Highlighter that memoizes results of another Highlighter,
+// keyed by file/line and options. Concurrent calls with the same key
+// wait for a single result. Memoized results are never invalidated,
+// so Memo is meant to be used for a single goroutine snapshot.
+type Memo struct {
+	hl      Highlighter
+	mu      sync.Mutex
+	entries map[memoKey]*memoEntry
+}
+
+// NewMemo returns Memo that highlights using hl.
+func NewMemo(hl Highlighter) *Memo {
+	return &Memo{hl: hl, entries: map[memoKey]*memoEntry{}}
+}
+
+func (m *Memo) Highlight(file profiler.FileLine, opts Options, hl *profiler.Highlight) error {
+	key := memoKey{FileLine: file, Options: opts}
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &memoEntry{}
+		m.entries[key] = entry
+	}
+	m.mu.Unlock()
+	entry.once.Do(func() {
+		entry.err = m.hl.Highlight(file, opts, &entry.hl)
+	})
+	*hl = entry.hl
+	return entry.err
+}
+
+// verifyKey identifies a verified call stack frame.
+type verifyKey struct {
+	profiler.FileLine
+	Package string
+	Method  string
+}
+
+// verifyEntry is a single memoized verification result.
+type verifyEntry struct {
+	once     sync.Once
+	mismatch *profiler.Mismatch
+	err      error
+}
+
+// VerifyMemo is a Verifier that memoizes results of another Verifier,
+// keyed by file/line, package and method. Concurrent calls with the
+// same key wait for a single result. Like Memo, it's meant to be used
+// for a single goroutine snapshot.
+type VerifyMemo struct {
+	v       Verifier
+	mu      sync.Mutex
+	entries map[verifyKey]*verifyEntry
+}
+
+// NewVerifyMemo returns VerifyMemo that verifies using v.
+func NewVerifyMemo(v Verifier) *VerifyMemo {
+	return &VerifyMemo{v: v, entries: map[verifyKey]*verifyEntry{}}
+}
+
+func (m *VerifyMemo) Verify(s profiler.CallStack) (*profiler.Mismatch, error) {
+	key := verifyKey{FileLine: s.FileLine, Package: s.Package, Method: s.Method}
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &verifyEntry{}
+		m.entries[key] = entry
+	}
+	m.mu.Unlock()
+	entry.once.Do(func() {
+		entry.mismatch, entry.err = m.v.Verify(s)
+	})
+	return entry.mismatch, entry.err
+}
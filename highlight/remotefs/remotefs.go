@@ -0,0 +1,124 @@
+// Package remotefs provides fs.FS reading source files from a remote sourcehandler.
+package remotefs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofu/gomon/highlight/internal/memfs"
+)
+
+// FS reads files from a remote http/sourcehandler.Handler. Names are
+// prefixed by their root type, same as highlightfs.RootFS, eg. "GOROOT/src/fmt/print.go".
+// Files not found remotely are remembered, and not requested again.
+type FS struct {
+	url    string
+	client *http.Client
+	// prefix of names, set by Sub
+	prefix string
+	// misses contains full names of files not found remotely, shared by Sub
+	misses *sync.Map
+}
+
+// New expects sourceURL to be the full URL of the remote sourcehandler.
+func New(sourceURL string) *FS {
+	if len(sourceURL) != 0 && !strings.Contains(sourceURL, "://") {
+		sourceURL = "http://" + sourceURL
+	}
+	return &FS{
+		url:    sourceURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+		misses: &sync.Map{},
+	}
+}
+
+func (f *FS) Open(name string) (fs.File, error) {
+	res, err := f.request(http.MethodGet, "open", name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return memfs.NewFile(name, data, modTime).Open(), nil
+}
+
+// Stat returns remote file info, without reading its content.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	res, err := f.request(http.MethodHead, "stat", name)
+	if err != nil {
+		return nil, err
+	}
+	_ = res.Body.Close()
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return &fileInfo{name: path.Base(name), size: res.ContentLength, modTime: modTime}, nil
+}
+
+// Sub returns FS with names relative to dir.
+func (f *FS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	sub := *f
+	sub.prefix = path.Join(f.prefix, dir)
+	return &sub, nil
+}
+
+// request sends remote request for file name, returning error for non-200 status.
+func (f *FS) request(method, op, name string) (*http.Response, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	full := path.Join(f.prefix, name)
+	root, file, ok := strings.Cut(full, "/")
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if _, miss := f.misses.Load(full); miss {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	query := url.Values{"root": {root}, "file": {file}}
+	req, err := http.NewRequest(method, f.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res, nil
+	case http.StatusNotFound:
+		f.misses.Store(full, struct{}{})
+		err = fs.ErrNotExist
+	default:
+		err = fmt.Errorf("unexpected status %s", res.Status)
+	}
+	_ = res.Body.Close()
+	return nil, &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// fileInfo of a remote file.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (f *fileInfo) Name() string       { return f.name }
+func (f *fileInfo) Size() int64        { return f.size }
+func (f *fileInfo) Mode() fs.FileMode  { return 0444 }
+func (f *fileInfo) ModTime() time.Time { return f.modTime }
+func (f *fileInfo) IsDir() bool        { return false }
+func (f *fileInfo) Sys() any           { return nil }
@@ -0,0 +1,68 @@
+package highlight
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters"
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/style"
+)
+
+// Terminal formats source code with ANSI escape sequences, prefixing
+// lines with their numbers, and marking the current line.
+type Terminal struct {
+	// TrueColor uses 24-bit colors, otherwise the 256-color palette.
+	TrueColor bool
+	// Style of tokens, style.Vulcan if nil.
+	Style *chroma.Style
+}
+
+// Range fills *profiler.Highlight with terminal output of a source code
+// segment, same as (*LineTokens).Range.
+func (t Terminal) Range(l *LineTokens, line, before, after int, hl *profiler.Highlight) error {
+	last := line + after
+	if last > l.Len() {
+		last = l.Len()
+	}
+	// prefix and suffix line numbers are aligned
+	width := len(strconv.Itoa(last))
+	return l.formatRange(line, before, after, hl, func(tokens []chroma.Token, baseLine, mark int) (string, error) {
+		return t.format(tokens, baseLine, mark, width)
+	})
+}
+
+// format returns terminal output of tokens, numbered from baseLine
+// and padded to width. If mark!=0, that line number is marked.
+func (t Terminal) format(tokens []chroma.Token, baseLine, mark, width int) (string, error) {
+	if len(tokens) == 0 {
+		return "", nil
+	}
+	var numbered []chroma.Token
+	for i, line := range chroma.SplitTokensIntoLines(tokens) {
+		n := baseLine + i
+		number := chroma.Token{Type: chroma.LineNumbers, Value: fmt.Sprintf("%*d │ ", width, n)}
+		if n == mark {
+			number = chroma.Token{Type: chroma.LineHighlight, Value: fmt.Sprintf("%*d ▶ ", width, n)}
+		}
+		numbered = append(numbered, number)
+		numbered = append(numbered, line...)
+		if len(line) == 0 || !strings.HasSuffix(line[len(line)-1].Value, "\n") {
+			numbered = append(numbered, chroma.Token{Type: chroma.Text, Value: "\n"})
+		}
+	}
+	formatter := formatters.TTY256
+	if t.TrueColor {
+		formatter = formatters.TTY16m
+	}
+	s := t.Style
+	if s == nil {
+		s = style.Vulcan
+	}
+	var buf bytes.Buffer
+	err := formatter.Format(&buf, s, chroma.Literator(numbered...))
+	return buf.String(), err
+}
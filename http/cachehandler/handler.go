@@ -0,0 +1,45 @@
+// Package cachehandler serves source cache statistics, and flushes the cache.
+package cachehandler
+
+import (
+	"net/http"
+
+	"github.com/gofu/gomon/highlight/highlightfs"
+	"github.com/gofu/gomon/http/serve"
+)
+
+// Cache of highlighted source files.
+type Cache interface {
+	// Stats returns cache statistics.
+	Stats() highlightfs.CacheStats
+	// Flush removes all cached files.
+	Flush()
+}
+
+// Handler serves cache statistics as JSON on GET,
+// and flushes the cache on POST, unless it's cross-origin.
+type Handler struct {
+	cache Cache
+}
+
+// New requires non-nil cache.
+func New(cache Cache) Handler {
+	return Handler{cache: cache}
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		if !serve.SameOrigin(r) {
+			http.Error(w, "cross-origin cache flush", http.StatusForbidden)
+			return
+		}
+		h.cache.Flush()
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	serve.JSON(w, r, h.cache.Stats())
+}
@@ -0,0 +1,101 @@
+// Package filehandler serves highlighted source files in HTML format.
+package filehandler
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/http/serve"
+	"github.com/gofu/gomon/http/statichandler"
+	"github.com/gofu/gomon/profiler"
+)
+
+// Handler serves ?root=&file=&line= requests, where root is a profiler.RootType,
+// and file is a path relative to the root. The whole file is shown with line
+// marked, next to a gutter of goroutines running each line.
+type Handler struct {
+	hl   highlight.Highlighter
+	prof profiler.Profiler
+}
+
+// New requires non-nil highlighter and profiler.
+func New(highlighter highlight.Highlighter, prof profiler.Profiler) *Handler {
+	return &Handler{hl: highlighter, prof: prof}
+}
+
+// URL returns the route URL showing file, scrolled to its line.
+func URL(route string, file profiler.FileLine) string {
+	query := url.Values{
+		"root": {string(file.Root)},
+		"file": {file.File},
+		"line": {strconv.Itoa(file.Line)},
+	}
+	return route + "?" + query.Encode() + "#" + highlight.LineIDPrefix + strconv.Itoa(file.Line)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := h.Execute(r.URL.Query())
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
+	data.Stylesheet = statichandler.StylesheetURL(statichandler.Theme(r))
+	serve.HTMLTemplate(w, r, tpl, data)
+}
+
+func (h *Handler) Execute(query url.Values) (Data, error) {
+	var data Data
+	data.Root = profiler.RootType(query.Get("root"))
+	data.File = query.Get("file")
+	switch data.Root {
+	case profiler.RootTypeProject, profiler.RootTypeGoRoot, profiler.RootTypeGoPath:
+	default:
+		return data, fmt.Errorf("invalid root %q", data.Root)
+	}
+	if !fs.ValidPath(data.File) || data.File == "." {
+		return data, fmt.Errorf("invalid file path %q", data.File)
+	}
+	if line := query.Get("line"); len(line) != 0 {
+		var err error
+		data.Line, err = strconv.Atoi(line)
+		if err != nil {
+			return data, err
+		}
+	}
+	// the line is marked by the template
+	whole := profiler.FileLine{Root: data.Root, File: data.File}
+	err := h.hl.Highlight(whole, highlight.Options{WholeFile: true}, &data.Highlight)
+	if err != nil {
+		return data, err
+	}
+	running, err := h.prof.Goroutines()
+	if err != nil {
+		return data, err
+	}
+	data.Gutter = gutter(running, data.Root, data.File)
+	return data, nil
+}
+
+// gutter returns goroutines running each line of file, indexed from line 1.
+func gutter(running []profiler.Goroutine, root profiler.RootType, file string) [][]profiler.Goroutine {
+	var lines [][]profiler.Goroutine
+	for _, gr := range running {
+		for _, s := range gr.CallStack {
+			if s.Root != root || s.File != file || s.Line < 1 {
+				continue
+			}
+			for len(lines) < s.Line {
+				lines = append(lines, nil)
+			}
+			if n := len(lines[s.Line-1]); n == 0 || lines[s.Line-1][n-1].ID != gr.ID {
+				// recursive frames count once
+				lines[s.Line-1] = append(lines[s.Line-1], gr)
+			}
+		}
+	}
+	return lines
+}
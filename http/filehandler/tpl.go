@@ -0,0 +1,63 @@
+package filehandler
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/profiler"
+)
+
+var (
+	//go:embed tpl.gohtml
+	tplData string
+	tpl     = template.Must(template.New("").Parse(tplData))
+)
+
+type Data struct {
+	profiler.FileLine
+	// Highlight of the whole file, without the current line marked,
+	// so it's cached once per file.
+	profiler.Highlight
+	// Gutter contains goroutines running each line, starting from line 1.
+	Gutter [][]profiler.Goroutine
+	// Stylesheet URL of the selected theme.
+	Stylesheet string
+}
+
+// LineID returns the HTML ID of the current line number.
+func (d Data) LineID() string {
+	return highlight.LineIDPrefix + strconv.Itoa(d.Line)
+}
+
+// lineTable starts the table of line numbers and source code, see html.LineNumbersInTable.
+const lineTable = `<table class="lntable"><tr>`
+
+// Source returns the highlighted file, with Gutter as the first column
+// of its table. The gutter is styled as line numbers, so its lines
+// align with the source code.
+func (d Data) Source() template.HTML {
+	if len(d.Gutter) == 0 {
+		// the highlighted file is trusted
+		return template.HTML(d.Prefix)
+	}
+	var gutter strings.Builder
+	gutter.WriteString(`<td class="lntd go-gutter"><pre class="chroma" title="Running goroutines">`)
+	for _, running := range d.Gutter {
+		gutter.WriteString(`<span class="lnt">`)
+		if len(running) != 0 {
+			var title strings.Builder
+			for _, gr := range running {
+				fmt.Fprintf(&title, "Go#%d %s %s\n", gr.ID, gr.Op, gr.Duration)
+			}
+			fmt.Fprintf(&gutter, `<span title="%s">&#9679;%d</span>`, html.EscapeString(title.String()), len(running))
+		}
+		gutter.WriteString("\n</span>")
+	}
+	gutter.WriteString("</pre></td>")
+	return template.HTML(strings.Replace(d.Prefix, lineTable, lineTable+gutter.String(), 1))
+}
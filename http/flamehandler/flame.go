@@ -0,0 +1,100 @@
+package flamehandler
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+)
+
+// Node of merged call stacks. Frames are merged if their function,
+// file and line are the same, regardless of arguments.
+type Node struct {
+	// Func is the function name, eg. "net/http.(*Server).Serve".
+	Func string
+	profiler.FileLine
+	// Caller is set for the "created by" frame.
+	Caller bool
+	// Count of goroutines passing through this node.
+	Count int
+	// Children called from this node, sorted by Func and position.
+	Children []*Node
+	index    map[string]*Node
+}
+
+// Merge returns the root node of call stacks of goroutines,
+// starting from the outermost frames.
+func Merge(goroutines []profiler.Goroutine) *Node {
+	root := &Node{Func: "all"}
+	for _, gr := range goroutines {
+		root.Count++
+		node := root
+		for _, s := range gr.CallStack {
+			node = node.child(s)
+			node.Count++
+		}
+	}
+	root.sort()
+	return root
+}
+
+func (n *Node) child(s profiler.CallStack) *Node {
+	fn := filter.Func(s)
+	key := fn + " " + string(s.Root) + "/" + s.File + ":" + strconv.Itoa(s.Line)
+	if c, ok := n.index[key]; ok {
+		return c
+	}
+	if n.index == nil {
+		n.index = map[string]*Node{}
+	}
+	c := &Node{Func: fn, FileLine: s.FileLine, Caller: s.Caller}
+	n.index[key] = c
+	n.Children = append(n.Children, c)
+	return c
+}
+
+func (n *Node) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if a.Func != b.Func {
+			return a.Func < b.Func
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	for _, c := range n.Children {
+		c.index = nil
+		c.sort()
+	}
+}
+
+// Rect is a laid out Node. Horizontal positions
+// are fractions of the root node's width.
+type Rect struct {
+	*Node
+	X, W  float64
+	Depth int
+	// Parent is the index of the parent Rect, -1 for the root.
+	Parent int
+}
+
+// Layout returns rects of root and its descendants, parents before
+// children, skipping nodes narrower than minWidth.
+func Layout(root *Node, minWidth float64) []Rect {
+	rects := []Rect{{Node: root, W: 1, Parent: -1}}
+	for i := 0; i < len(rects); i++ {
+		parent := rects[i]
+		x := parent.X
+		for _, c := range parent.Children {
+			w := float64(c.Count) / float64(root.Count)
+			if w >= minWidth {
+				rects = append(rects, Rect{Node: c, X: x, W: w, Depth: parent.Depth + 1, Parent: i})
+			}
+			x += w
+		}
+	}
+	return rects
+}
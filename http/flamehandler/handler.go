@@ -0,0 +1,61 @@
+// Package flamehandler serves call stacks of running goroutines,
+// merged into an SVG flame graph.
+package flamehandler
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gofu/gomon/http/router"
+	"github.com/gofu/gomon/http/serve"
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+)
+
+// MinWidth is the fraction of all goroutines, under which frames are not drawn.
+const MinWidth = 0.001
+
+// Handler serves an icicle chart of merged call stacks, outermost frames
+// on top, widths proportional to the number of goroutines. Goroutines are
+// filtered by the same query parameters as the HTML page, and frames link
+// to the HTML page filtered to goroutines passing through them.
+type Handler struct {
+	prof   profiler.Profiler
+	routes router.Router
+}
+
+// New requires non-nil profiler.
+func New(prof profiler.Profiler, routes router.Router) *Handler {
+	return &Handler{prof: prof, routes: routes}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := h.Execute(r.URL.Query())
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
+	serve.HTMLTemplate(w, r, tpl, data)
+}
+
+// Execute returns the flame graph of goroutines matching the query filter.
+func (h *Handler) Execute(query url.Values) (Data, error) {
+	data := Data{routes: h.routes, query: query}
+	var err error
+	if data.Filter, err = filter.Parse(query); err != nil {
+		return data, err
+	}
+	running, err := h.prof.Goroutines()
+	if err != nil {
+		return data, err
+	}
+	data.Total = len(running)
+	running, data.Skipped = data.Filter.Filter(running)
+	data.Rects = Layout(Merge(running), MinWidth)
+	for _, r := range data.Rects {
+		if r.Depth > data.Depth {
+			data.Depth = r.Depth
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,103 @@
+package flamehandler
+
+import (
+	_ "embed"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"net/url"
+	"strconv"
+
+	"github.com/gofu/gomon/http/paging"
+	"github.com/gofu/gomon/http/router"
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+)
+
+var (
+	//go:embed tpl.gohtml
+	tplData string
+	tpl     = template.Must(template.New("").Funcs(template.FuncMap{
+		"percent": func(f float64) string { return strconv.FormatFloat(f*100, 'f', 4, 64) + "%" },
+	}).Parse(tplData))
+)
+
+// RowHeight of a frame in pixels.
+const RowHeight = 18
+
+// Data of the flame graph page.
+type Data struct {
+	filter.Filter
+	Total   int
+	Skipped int
+	// Rects of merged frames, the first one is the root of all goroutines.
+	Rects []Rect
+	// Depth of the deepest frame.
+	Depth  int
+	routes router.Router
+	query  url.Values
+}
+
+// RowHeight returns the height of a frame in pixels.
+func (d Data) RowHeight() int { return RowHeight }
+
+// Height of the chart in pixels.
+func (d Data) Height() int { return (d.Depth + 1) * RowHeight }
+
+// Y returns the vertical position of r in pixels.
+func (d Data) Y(r Rect) int { return r.Depth * RowHeight }
+
+// HTMLURL returns the URL of the HTML page with the same filter.
+func (d Data) HTMLURL() string {
+	return paging.URL(d.routes.HTML, d.query, 0)
+}
+
+// FrameURL returns the URL of the HTML page, narrowed to goroutines
+// passing through the frame of r, see filter.Narrow.
+func (d Data) FrameURL(r Rect) string {
+	if r.Parent == -1 {
+		return d.HTMLURL()
+	}
+	condition := string(filter.FieldRegexp) + ":" + filter.FrameRegexp(r.Func, r.File, r.Line)
+	return paging.URL(d.routes.HTML, filter.Narrow(d.query, condition), 0)
+}
+
+// Label returns the text drawn in r.
+func (d Data) Label(r Rect) string {
+	if r.Parent == -1 {
+		return fmt.Sprintf("all (%d goroutines)", r.Count)
+	}
+	if r.Caller {
+		return "created by " + r.Func
+	}
+	return r.Func
+}
+
+// Title returns the tooltip of r.
+func (d Data) Title(r Rect) string {
+	share := fmt.Sprintf("%d goroutines (%.1f%%)", r.Count, r.W*100)
+	if r.Parent == -1 {
+		return share
+	}
+	return d.Label(r) + "\n" + r.File + ":" + strconv.Itoa(r.Line) + "\n" + share
+}
+
+// hues of frames by root type, other frames are grey.
+var hues = map[profiler.RootType]int{
+	profiler.RootTypeProject: 110,
+	profiler.RootTypeGoRoot:  20,
+	profiler.RootTypeGoPath:  200,
+}
+
+// Fill returns the color of r, by its root type. The lightness
+// depends on the function, so neighbouring frames differ.
+func (d Data) Fill(r Rect) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(r.Func))
+	lightness := 30 + h.Sum32()%20
+	hue, ok := hues[r.Root]
+	if !ok {
+		return fmt.Sprintf("hsl(0, 0%%, %d%%)", lightness)
+	}
+	return fmt.Sprintf("hsl(%d, 55%%, %d%%)", hue, lightness)
+}
@@ -0,0 +1,122 @@
+package htmlhandler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofu/gomon/profiler"
+)
+
+// FrameMode selects which call stack frames are shown.
+type FrameMode string
+
+const (
+	// FramesAll shows all frames.
+	FramesAll FrameMode = ""
+	// FramesProject shows only project frames.
+	FramesProject FrameMode = "project"
+	// FramesCollapse collapses consecutive GOROOT/GOPATH/CGO frames into one row.
+	FramesCollapse FrameMode = "collapse"
+	// FramesNoRuntime hides runtime internals.
+	FramesNoRuntime FrameMode = "noruntime"
+)
+
+// FrameModes lists all frame modes, except FramesAll.
+var FrameModes = []FrameMode{FramesProject, FramesCollapse, FramesNoRuntime}
+
+// ParseFrameMode returns a valid FrameMode, or an error.
+func ParseFrameMode(s string) (FrameMode, error) {
+	switch mode := FrameMode(s); mode {
+	case FramesAll, FramesProject, FramesCollapse, FramesNoRuntime:
+		return mode, nil
+	default:
+		return FramesAll, fmt.Errorf("invalid frames mode %q", s)
+	}
+}
+
+// Label returns a human-readable name of mode.
+func (m FrameMode) Label() string {
+	switch m {
+	case FramesAll:
+		return "All"
+	case FramesProject:
+		return "Project only"
+	case FramesCollapse:
+		return "Collapse libraries"
+	case FramesNoRuntime:
+		return "Hide runtime"
+	default:
+		return string(m)
+	}
+}
+
+// Frame is a shown call stack frame, or a row of collapsed frames.
+type Frame struct {
+	// CallStack is the shown frame, if Collapsed is empty.
+	profiler.CallStack
+	// Collapsed consecutive frames, shown as one row.
+	Collapsed []profiler.CallStack
+}
+
+// Packages returns the distinct packages of Collapsed frames.
+func (f Frame) Packages() string {
+	var pkgs []string
+	for i, s := range f.Collapsed {
+		if i == 0 || s.Package != f.Collapsed[i-1].Package {
+			pkgs = append(pkgs, s.Package)
+		}
+	}
+	return strings.Join(pkgs, ", ")
+}
+
+// Visible returns frames of stack shown in mode m.
+func (m FrameMode) Visible(stack []profiler.CallStack) []Frame {
+	frames := make([]Frame, 0, len(stack))
+	for i, s := range stack {
+		switch {
+		case m.Shown(stack, i):
+			frames = append(frames, Frame{CallStack: s})
+		case m != FramesCollapse:
+		case len(frames) != 0 && len(frames[len(frames)-1].Collapsed) != 0:
+			last := &frames[len(frames)-1]
+			last.Collapsed = append(last.Collapsed, s)
+		default:
+			frames = append(frames, Frame{Collapsed: []profiler.CallStack{s}})
+		}
+	}
+	return frames
+}
+
+// Shown reports whether stack[i] is shown in mode m, and not
+// hidden or collapsed. Only shown frames are highlighted.
+func (m FrameMode) Shown(stack []profiler.CallStack, i int) bool {
+	switch m {
+	case FramesProject:
+		return stack[i].Root == profiler.RootTypeProject
+	case FramesCollapse:
+		// a single library frame between project frames is not collapsed
+		return !isLibrary(stack[i]) ||
+			(i == 0 || !isLibrary(stack[i-1])) && (i == len(stack)-1 || !isLibrary(stack[i+1]))
+	case FramesNoRuntime:
+		return !isRuntime(stack[i])
+	default:
+		return true
+	}
+}
+
+// isLibrary reports whether s is not a project frame.
+func isLibrary(s profiler.CallStack) bool {
+	return s.Root != profiler.RootTypeProject
+}
+
+// isRuntime reports whether s is a frame of Go runtime internals,
+// including runtime functions linked into other standard packages.
+func isRuntime(s profiler.CallStack) bool {
+	if s.Root != profiler.RootTypeGoRoot && s.Root != profiler.RootTypeCGo {
+		return false
+	}
+	return s.Package == "runtime" ||
+		strings.HasPrefix(s.Package, "runtime/internal/") ||
+		strings.HasPrefix(s.Package, "internal/") ||
+		strings.HasPrefix(s.Method, "runtime_")
+}
@@ -0,0 +1,233 @@
+package htmlhandler
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofu/gomon/env/gitblame"
+	"github.com/gofu/gomon/http/paging"
+	"github.com/gofu/gomon/http/serve"
+	"github.com/gofu/gomon/http/statichandler"
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/group"
+	"github.com/gofu/gomon/style"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	//go:embed goroutine.gohtml
+	goroutineTplData string
+	// goroutineTpl shares the "stack" template with tpl
+	goroutineTpl = template.Must(template.Must(tpl.Clone()).Parse(goroutineTplData))
+)
+
+// RelatedLimit is the max number of related goroutines listed in Detail.
+const RelatedLimit = 100
+
+// SharedArgs lists pointers passed to both a goroutine and a related one.
+type SharedArgs struct {
+	// ID of the related goroutine.
+	ID int `json:"id"`
+	// Pointers passed as arguments to both goroutines.
+	Pointers []string `json:"pointers"`
+}
+
+// Detail of a single goroutine, and goroutines related to it.
+type Detail struct {
+	profiler.Goroutine
+	// CreatedBy is the frame that started the goroutine, without highlighting
+	// and the parent goroutine suffix, or nil for the main goroutine.
+	CreatedBy *profiler.CallStack `json:"createdBy,omitempty"`
+	// ParentID is the ID of the goroutine that started this one, if known.
+	ParentID int `json:"parentId,omitempty"`
+	// SameStack lists up to RelatedLimit IDs of goroutines with the
+	// same call stack, regardless of arguments.
+	SameStack      []int `json:"sameStack"`
+	SameStackTotal int   `json:"sameStackTotal"`
+	// SharedArgs lists up to RelatedLimit goroutines called
+	// with the same pointer arguments.
+	SharedArgs      []SharedArgs `json:"sharedArgs"`
+	SharedArgsTotal int          `json:"sharedArgsTotal"`
+}
+
+// GoroutineData for the goroutine page.
+type GoroutineData struct {
+	Data
+	Detail Detail
+}
+
+// ListURL returns the URL of the list of goroutines, with the same query.
+func (d GoroutineData) ListURL() string {
+	return paging.URL(d.routes.HTML, d.query, 0)
+}
+
+// JSONURL returns the URL of the goroutine in JSON.
+func (d GoroutineData) JSONURL() string {
+	return d.GoroutineURL(d.Detail.ID) + ".json"
+}
+
+// GoroutineHandler serves a single goroutine, see Handler.Goroutine.
+type GoroutineHandler struct {
+	h *Handler
+}
+
+// Goroutine returns a handler serving a goroutine at routes.Goroutine+"{id}"
+// in HTML, or routes.Goroutine+"{id}.json" in JSON, with the highlighted
+// context of every frame, and goroutines related to it. Goroutines are
+// read from the same profiler as the list of goroutines.
+func (h *Handler) Goroutine() GoroutineHandler {
+	return GoroutineHandler{h: h}
+}
+
+func (g GoroutineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, g.h.routes.Goroutine)
+	isJSON := strings.HasSuffix(name, ".json")
+	name = strings.TrimSuffix(name, ".json")
+	id, err := strconv.Atoi(name)
+	if err != nil {
+		serve.Error(w, r, fmt.Errorf("invalid goroutine ID %q", name))
+		return
+	}
+	data, err := g.Execute(r.Context(), id, r.URL.Query())
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
+	if isJSON {
+		serve.JSON(w, r, data.Detail)
+		return
+	}
+	data.Theme = statichandler.Theme(r)
+	data.Themes = style.Names()
+	statichandler.RememberTheme(w, r, data.Theme)
+	serve.HTMLTemplate(w, r, goroutineTpl, data)
+}
+
+// Execute returns the goroutine with ID id. By default, frames show their
+// whole enclosing function, while query accepts the same options as Handler.
+func (g GoroutineHandler) Execute(ctx context.Context, id int, query url.Values) (GoroutineData, error) {
+	h := g.h
+	data := GoroutineData{Data: Data{routes: h.routes, linker: h.linker, query: query}}
+	var err error
+	data.Request, err = ParseRequest(query)
+	if err != nil {
+		return data, err
+	}
+	if len(query.Get("lines")) == 0 {
+		data.Func = true
+	}
+	data.MarkupLimit = 0
+	running, err := h.prof.Goroutines()
+	if err != nil {
+		return data, err
+	}
+	data.Total = len(running)
+	i := indexOf(running, id)
+	if i == -1 {
+		return data, fmt.Errorf("goroutine %d is not running", id)
+	}
+	data.Detail = related(running, i)
+	marked := []profiler.Goroutine{data.Detail.Goroutine}
+	h.linker.Resolve(marked)
+	if data.WrapSize >= 0 {
+		if err = MarkupGoroutines(ctx, marked, h.hl, data.MarkupOptions); err != nil {
+			return data, err
+		}
+	}
+	if h.blamer != nil {
+		if err = gitblame.Goroutines(ctx, marked, h.blamer); err != nil {
+			return data, err
+		}
+	}
+	return data, nil
+}
+
+func indexOf(running []profiler.Goroutine, id int) int {
+	for i, gr := range running {
+		if gr.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// related returns the detail of running[i].
+func related(running []profiler.Goroutine, i int) Detail {
+	gr := running[i]
+	d := Detail{Goroutine: gr, SameStack: []int{}, SharedArgs: []SharedArgs{}}
+	if len(gr.CallStack) != 0 && gr.CallStack[0].Caller {
+		createdBy := gr.CallStack[0]
+		createdBy.Method, d.ParentID = profiler.CutCreatedIn(createdBy.Method)
+		d.CreatedBy = &createdBy
+	}
+	signature := group.Signature(gr, group.ModeIgnoreArgs)
+	pointers := argPointers(gr)
+	for j, other := range running {
+		if j == i {
+			continue
+		}
+		if group.Signature(other, group.ModeIgnoreArgs) == signature {
+			d.SameStackTotal++
+			if len(d.SameStack) < RelatedLimit {
+				d.SameStack = append(d.SameStack, other.ID)
+			}
+		}
+		if len(pointers) == 0 {
+			continue
+		}
+		var shared []string
+		for _, p := range argPointerList(other) {
+			if _, ok := pointers[p]; ok && !slices.Contains(shared, p) {
+				shared = append(shared, p)
+			}
+		}
+		if len(shared) != 0 {
+			d.SharedArgsTotal++
+			if len(d.SharedArgs) < RelatedLimit {
+				d.SharedArgs = append(d.SharedArgs, SharedArgs{ID: other.ID, Pointers: shared})
+			}
+		}
+	}
+	return d
+}
+
+// hexRegexp matches hexadecimal call arguments, the opening brace of
+// interfaces and strings, and the "?" suffix of inaccurate values.
+var hexRegexp = regexp.MustCompile(`(\{?)0x([0-9a-f]+)(\??)`)
+
+// minPointer is the lowest argument value treated as a pointer,
+// so small integers and lengths are not matched.
+const minPointer = 0x10000
+
+// argPointerList returns hexadecimal call arguments of gr, that look like
+// pointers. Type words of interfaces, that are shared by all values of
+// the same type, and possibly inaccurate values are skipped.
+func argPointerList(gr profiler.Goroutine) []string {
+	var pointers []string
+	for _, s := range gr.CallStack {
+		for _, m := range hexRegexp.FindAllStringSubmatch(s.Args, -1) {
+			if len(m[1]) != 0 || len(m[3]) != 0 {
+				continue
+			}
+			if v, err := strconv.ParseUint(m[2], 16, 64); err == nil && v >= minPointer {
+				pointers = append(pointers, "0x"+m[2])
+			}
+		}
+	}
+	return pointers
+}
+
+func argPointers(gr profiler.Goroutine) map[string]struct{} {
+	pointers := map[string]struct{}{}
+	for _, p := range argPointerList(gr) {
+		pointers[p] = struct{}{}
+	}
+	return pointers
+}
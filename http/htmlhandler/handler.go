@@ -7,24 +7,43 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/gofu/gomon/editor"
+	"github.com/gofu/gomon/env/gitblame"
 	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/http/paging"
+	"github.com/gofu/gomon/http/router"
 	"github.com/gofu/gomon/http/serve"
+	"github.com/gofu/gomon/http/statichandler"
 	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+	"github.com/gofu/gomon/profiler/group"
+	"github.com/gofu/gomon/profiler/order"
+	"github.com/gofu/gomon/style"
 	"golang.org/x/exp/constraints"
 )
 
 // Handler serves running goroutines as HTML. The source code of the
 // call stack is also optionally showed as styled/colored HTML.
 type Handler struct {
-	prof profiler.Profiler
-	hl   highlight.Highlighter
+	prof   profiler.Profiler
+	hl     highlight.Highlighter
+	routes router.Router
+	linker editor.Linker
+	blamer gitblame.Blamer
 }
 
-// New requires non-nil highlighter and profiler.
-func New(highlighter highlight.Highlighter, prof profiler.Profiler) *Handler {
+// New requires non-nil highlighter and profiler. Frames link to
+// the routes source view, are resolved to local files and
+// linked to an editor by linker.
+// If blamer is non-nil, highlighted project frames are annotated
+// with the last change of their line.
+func New(highlighter highlight.Highlighter, prof profiler.Profiler, routes router.Router, linker editor.Linker, blamer gitblame.Blamer) *Handler {
 	return &Handler{
-		prof: prof,
-		hl:   highlighter,
+		prof:   prof,
+		hl:     highlighter,
+		routes: routes,
+		linker: linker,
+		blamer: blamer,
 	}
 }
 
@@ -34,20 +53,33 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		serve.Error(w, r, err)
 		return
 	}
+	data.Theme = statichandler.Theme(r)
+	data.Themes = style.Names()
+	statichandler.RememberTheme(w, r, data.Theme)
 	serve.HTMLTemplate(w, r, tpl, data)
 }
 
+// Durations are the suggested blocked durations of goroutines.
+var Durations = fibSlice(20, time.Minute)
+
 var (
-	indexDurations = fibSlice(20, time.Minute)
-	indexMarkups   = fibSlice(10, 1)
-	indexContexts  = indexMarkups
+	indexMarkups  = fibSlice(10, 1)
+	indexContexts = indexMarkups
 )
 
 func (h *Handler) Execute(ctx context.Context, query url.Values) (Data, error) {
 	data := Data{
-		Durations: indexDurations,
-		Markups:   indexMarkups,
-		Contexts:  indexContexts,
+		Durations:  Durations,
+		Markups:    indexMarkups,
+		Contexts:   indexContexts,
+		Fields:     filter.Fields,
+		FrameModes: FrameModes,
+		Modes:      group.Modes,
+		SortKeys:   order.Keys,
+		Limits:     paging.Limits,
+		routes:     h.routes,
+		linker:     h.linker,
+		query:      query,
 	}
 	running, err := h.prof.Goroutines()
 	if err != nil {
@@ -59,8 +91,28 @@ func (h *Handler) Execute(ctx context.Context, query url.Values) (Data, error) {
 		return data, err
 	}
 	data.Running, data.Skipped = data.Filter.Filter(running)
+	data.Order.Sort(data.Running, data.Group)
+	// grouped goroutines are highlighted once per group
+	var marked []profiler.Goroutine
+	if data.Group != group.ModeNone {
+		data.Groups = paging.Slice(&data.Page, group.Goroutines(data.Running, data.Group))
+		marked = group.Representatives(data.Groups)
+	} else {
+		data.Running = paging.Slice(&data.Page, data.Running)
+		marked = data.Running
+	}
+	h.linker.Resolve(marked)
 	if data.WrapSize >= 0 {
-		err = MarkupGoroutines(ctx, data.Running, h.hl, data.MarkupOptions)
+		err = MarkupGoroutines(ctx, marked, h.hl, data.MarkupOptions)
+		if err != nil {
+			return data, err
+		}
+	}
+	if h.blamer != nil && data.WrapSize >= 0 {
+		if data.MarkupLimit != 0 && data.MarkupLimit < len(marked) {
+			marked = marked[:data.MarkupLimit]
+		}
+		err = gitblame.Goroutines(ctx, marked, h.blamer)
 		if err != nil {
 			return data, err
 		}
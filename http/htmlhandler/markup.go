@@ -2,6 +2,8 @@ package htmlhandler
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"runtime"
 
 	"github.com/gofu/gomon/highlight"
@@ -10,11 +12,23 @@ import (
 )
 
 // MarkupGoroutines fills highlight data (HTML) for provided goroutines.
+// If highlighter implements highlight.Verifier, frames are verified first.
+// Frames whose local source file is not found are not highlighted.
+// Frames at the same file/line are highlighted and verified only once,
+// and frames hidden or collapsed by options.Frames are not highlighted.
 func MarkupGoroutines(ctx context.Context, goroutines []profiler.Goroutine, highlighter highlight.Highlighter, options MarkupOptions) error {
-	opts := highlight.Options{WrapSize: options.WrapSize}
+	var verifier highlight.Verifier
+	if v, ok := highlighter.(highlight.Verifier); ok {
+		verifier = highlight.NewVerifyMemo(v)
+	}
+	return markupGoroutines(ctx, goroutines, highlight.NewMemo(highlighter), verifier, options)
+}
+
+// markupGoroutines highlights frames of goroutines, after verifying them if verifier is non-nil.
+func markupGoroutines(ctx context.Context, goroutines []profiler.Goroutine, highlighter highlight.Highlighter, verifier highlight.Verifier, options MarkupOptions) error {
+	opts := options.Options
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(runtime.NumCPU())
-	var err error
 	for i, gr := range goroutines {
 		if options.MarkupLimit != 0 && i >= options.MarkupLimit {
 			break
@@ -27,9 +41,26 @@ func MarkupGoroutines(ctx context.Context, goroutines []profiler.Goroutine, high
 					return ctx.Err()
 				default:
 				}
+				if !options.Frames.Shown(gr.CallStack, j) {
+					continue
+				}
 				s := &gr.CallStack[j]
+				var err error
+				if verifier != nil {
+					s.Mismatch, err = verifier.Verify(*s)
+					if err != nil {
+						return err
+					}
+					if s.Mismatch != nil && s.Mismatch.Warning == highlight.NotFound {
+						continue
+					}
+				}
 				err = highlighter.Highlight(s.FileLine, opts, &s.Highlight)
-				if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					// verifiers only check some files, eg. .go files
+					s.Mismatch = &profiler.Mismatch{Warning: highlight.NotFound}
+					continue
+				} else if err != nil {
 					return err
 				}
 			}
@@ -0,0 +1,99 @@
+package htmlhandler
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofu/gomon/env"
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/highlight/highlightfs"
+	"github.com/gofu/gomon/profiler"
+)
+
+// benchFiles are project files that benchmark frames point into.
+var benchFiles = []string{
+	"env/env.go",
+	"highlight/highlight.go",
+	"highlight/highlightfs/fs.go",
+	"http/htmlhandler/tpl.go",
+	"http/htmlhandler/handler.go",
+	"http/htmlhandler/markup.go",
+	"profiler/profiler.go",
+	"server/server.go",
+}
+
+// benchGoroutines generates n goroutines, sharing stacks number of
+// distinct call stacks, each having depth frames in benchFiles of root.
+func benchGoroutines(b *testing.B, root string, n, stacks, depth int) []profiler.Goroutine {
+	lines := make([]int, len(benchFiles))
+	for i, file := range benchFiles {
+		data, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			b.Fatal(err)
+		}
+		lines[i] = bytes.Count(data, []byte("\n"))
+	}
+	shared := make([][]profiler.CallStack, stacks)
+	for i := range shared {
+		for j := 0; j < depth; j++ {
+			k := (i + j) % len(benchFiles)
+			shared[i] = append(shared[i], profiler.CallStack{
+				FileLine: profiler.FileLine{
+					Root: profiler.RootTypeProject,
+					File: benchFiles[k],
+					Line: 1 + (i*31+j*17)%lines[k],
+				},
+				Package: "github.com/gofu/gomon",
+				Method:  "bench",
+			})
+		}
+	}
+	goroutines := make([]profiler.Goroutine, n)
+	for i := range goroutines {
+		goroutines[i] = profiler.Goroutine{ID: i + 1, CallStack: shared[i%stacks]}
+	}
+	return goroutines
+}
+
+// BenchmarkMarkupGoroutines highlights a dump of many goroutines
+// sharing few call stacks, with warm source file cache, with and
+// without memoized frames.
+func BenchmarkMarkupGoroutines(b *testing.B) {
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		b.Fatal(err)
+	}
+	goroutines := benchGoroutines(b, root, 20000, 12, 8)
+	hl := &highlightfs.FS{Env: env.Env{Root: env.NormalizePath(root)}}
+	opts := MarkupOptions{Options: highlight.Options{WrapSize: 5}}
+	benchmarks := []struct {
+		name   string
+		markup func(run []profiler.Goroutine) error
+	}{
+		{name: "memo", markup: func(run []profiler.Goroutine) error {
+			return MarkupGoroutines(context.Background(), run, hl, opts)
+		}},
+		{name: "no memo", markup: func(run []profiler.Goroutine) error {
+			return markupGoroutines(context.Background(), run, hl, hl, opts)
+		}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			run := make([]profiler.Goroutine, len(goroutines))
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				for j, g := range goroutines {
+					g.CallStack = append([]profiler.CallStack(nil), g.CallStack...)
+					run[j] = g
+				}
+				b.StartTimer()
+				if err := bm.markup(run); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -7,8 +7,16 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gofu/gomon/editor"
 	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/http/filehandler"
+	"github.com/gofu/gomon/http/paging"
+	"github.com/gofu/gomon/http/router"
+	"github.com/gofu/gomon/http/statichandler"
 	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+	"github.com/gofu/gomon/profiler/group"
+	"github.com/gofu/gomon/profiler/order"
 )
 
 var (
@@ -17,73 +25,43 @@ var (
 	tpl     = template.Must(template.New("").Funcs(template.FuncMap{
 		"revIndex": func(index, length int) (revIndex int) { return (length - 1) - index },
 		"sub":      func(a, b int) int { return a - b },
+		"add":      func(a, b int) int { return a + b },
 		"rawHTML":  func(s string) template.HTML { return template.HTML(s) },
 	}).Parse(tplData))
 )
 
-type Filter struct {
-	// MinDuration duration of goroutines to show.
-	MinDuration time.Duration
-	// MaxDuration duration of goroutines to show.
-	MaxDuration time.Duration
-}
-
-func (f Filter) IncludeAll() bool {
-	return f.MinDuration == 0 && f.MaxDuration == 0
-}
-
-func (f Filter) Include(gr profiler.Goroutine) bool {
-	if f.MinDuration != 0 && gr.Duration < f.MinDuration {
-		return false
-	}
-	if f.MaxDuration != 0 && gr.Duration > f.MaxDuration {
-		return false
-	}
-	return true
-}
-
-func (f Filter) Filter(gs []profiler.Goroutine) ([]profiler.Goroutine, int) {
-	var skipped int
-	if f.IncludeAll() {
-		return gs, 0
-	}
-	var filtered []profiler.Goroutine
-	for _, g := range gs {
-		if !f.Include(g) {
-			skipped++
-			continue
-		}
-		filtered = append(filtered, g)
-	}
-	return filtered, skipped
-}
-
 type MarkupOptions struct {
 	// MarkupLimit is the max number of highlighted goroutines.
 	MarkupLimit int
+	// Frames mode hides or collapses frames, which are not highlighted.
+	Frames FrameMode
 	highlight.Options
 }
 
+// linesFunc is the "lines" query value, that shows whole enclosing functions.
+const linesFunc = "func"
+
 type Request struct {
-	Filter
+	filter.Filter
 	MarkupOptions
+	// Group goroutines with identical call stacks, if set.
+	Group group.Mode
+	// Order of goroutines, or groups if Group is set.
+	Order order.Order
+	// Page of goroutines, or groups if Group is set.
+	Page paging.Page
 }
 
+// DefaultLimit is the number of goroutines or groups per page, if not set.
+const DefaultLimit = 100
+
 func ParseRequest(query url.Values) (Request, error) {
 	var data Request
 	var errs []error
 	var err error
-	if min := query.Get("min"); len(min) != 0 {
-		data.MinDuration, err = time.ParseDuration(min)
-		if err != nil {
-			errs = append(errs, err)
-		}
-	}
-	if max := query.Get("max"); len(max) != 0 {
-		data.MaxDuration, err = time.ParseDuration(max)
-		if err != nil {
-			errs = append(errs, err)
-		}
+	data.Filter, err = filter.Parse(query)
+	if err != nil {
+		errs = append(errs, err)
 	}
 	if markupLimit := query.Get("markup"); len(markupLimit) != 0 {
 		data.MarkupLimit, err = strconv.Atoi(markupLimit)
@@ -91,12 +69,30 @@ func ParseRequest(query url.Values) (Request, error) {
 			errs = append(errs, err)
 		}
 	}
-	if linesStr := query.Get("lines"); len(linesStr) != 0 {
+	if linesStr := query.Get("lines"); linesStr == linesFunc {
+		data.Func = true
+	} else if len(linesStr) != 0 {
 		data.WrapSize, err = strconv.Atoi(linesStr)
 		if err != nil {
 			errs = append(errs, err)
 		}
 	}
+	data.Frames, err = ParseFrameMode(query.Get("frames"))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	data.Group, err = group.ParseMode(query.Get("group"))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	data.Order, err = order.Parse(query)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	data.Page, err = paging.Parse(query, DefaultLimit)
+	if err != nil {
+		errs = append(errs, err)
+	}
 	if len(errs) == 0 {
 		return data, nil
 	}
@@ -111,4 +107,79 @@ type Data struct {
 	Total     int
 	Running   []profiler.Goroutine
 	Skipped   int
+	// Fields that filter conditions can match.
+	Fields []filter.Field
+	// FrameModes of showing call stack frames.
+	FrameModes []FrameMode
+	// Limits of goroutines per page.
+	Limits []int
+	// SortKeys of ordering goroutines.
+	SortKeys []order.Key
+	// Modes of grouping goroutines.
+	Modes []group.Mode
+	// Groups of Running goroutines, if Group is set.
+	Groups []group.Group
+	// Theme of highlighted source code, see statichandler.Theme.
+	Theme  string
+	Themes []string
+	routes router.Router
+	linker editor.Linker
+	query  url.Values
+}
+
+// SourceURL returns the URL of the source view of a frame.
+func (d Data) SourceURL(s profiler.CallStack) string {
+	return filehandler.URL(d.routes.Source, s.FileLine)
+}
+
+// PageURL returns the URL of the page at offset, with the same query.
+func (d Data) PageURL(offset int) string {
+	return paging.URL(d.routes.HTML, d.query, offset)
+}
+
+// GoroutineURL returns the URL of the goroutine page.
+func (d Data) GoroutineURL(id int) string {
+	return d.routes.Goroutine + strconv.Itoa(id)
+}
+
+// LiveURL returns the URL of the live page, with the same query.
+func (d Data) LiveURL() string {
+	return paging.URL(d.routes.Live, d.query, 0)
+}
+
+// FlameURL returns the URL of the flame graph, with the same query.
+func (d Data) FlameURL() string {
+	return paging.URL(d.routes.Flame, d.query, 0)
+}
+
+// SummaryURL returns the URL of the summary, with the same query.
+func (d Data) SummaryURL() string {
+	return paging.URL(d.routes.Summary, d.query, 0)
+}
+
+// EditorURL returns the editor deep link of a frame, or an empty string.
+func (d Data) EditorURL(s profiler.CallStack) template.URL {
+	// the template is configured by the user, and abs is escaped
+	return template.URL(d.linker.URL(s))
+}
+
+// Stack is the template data of a goroutine's call stack.
+type Stack struct {
+	Data
+	profiler.Goroutine
+}
+
+// Stack returns the template data of gr's call stack.
+func (d Data) Stack(gr profiler.Goroutine) Stack {
+	return Stack{Data: d, Goroutine: gr}
+}
+
+// Visible returns the call stack frames shown in the selected frames mode.
+func (s Stack) Visible() []Frame {
+	return s.Frames.Visible(s.CallStack)
+}
+
+// Stylesheet returns the URL of the selected theme stylesheet.
+func (d Data) Stylesheet() string {
+	return statichandler.StylesheetURL(d.Theme)
 }
@@ -9,7 +9,7 @@ import (
 	"github.com/gofu/gomon/http/serve"
 )
 
-// Handler serves static index page.
+// Handler serves index page, with static links.
 type Handler struct {
 	// Data to pass to template.
 	Data
@@ -21,7 +21,7 @@ var (
 	tpl     = template.Must(template.New("").Parse(tplData))
 )
 
-// New returns Handler that serves static data.
+// New returns Handler that serves data.
 func New(data Data) Handler {
 	return Handler{Data: data}
 }
@@ -1,5 +1,13 @@
 package indexhandler
 
+import (
+	"github.com/gofu/gomon/http/timeline"
+	"github.com/gofu/gomon/profiler/history"
+)
+
+// TimelineOps is the number of ops charted on the index page.
+const TimelineOps = 5
+
 // Link shown on the index page.
 type Link struct {
 	// Text of the link.
@@ -16,4 +24,23 @@ type Data struct {
 	ProfilerSource string
 	// Links to list on the index page.
 	Links []Link
+	// History of goroutine counts, optional.
+	History *history.History
+}
+
+// Timeline returns sparklines of the total number of goroutines,
+// and of the most common ops, or nil without History.
+func (d Data) Timeline() []timeline.Chart {
+	if d.History == nil {
+		return nil
+	}
+	points := d.History.Points()
+	charts := []timeline.Chart{timeline.Sparkline(points)}
+	for _, s := range history.Ops(points, TimelineOps) {
+		chart := timeline.Sparkline(points)
+		chart.Title, chart.Series = s.Name, []history.Series{s}
+		charts = append(charts, chart)
+	}
+	charts[0].Title = "total"
+	return charts
 }
@@ -4,25 +4,93 @@ package jsonhandler
 import (
 	"net/http"
 
+	"github.com/gofu/gomon/editor"
+	"github.com/gofu/gomon/env/gitblame"
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/http/paging"
 	"github.com/gofu/gomon/http/serve"
 	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+	"github.com/gofu/gomon/profiler/group"
+	"github.com/gofu/gomon/profiler/order"
 )
 
-// Handler serves running goroutines as JSON.
+// Handler serves running goroutines as JSON, filtered and
+// sorted by the same query parameters as the HTML page.
+// All goroutines are served as an array, unless offset or
+// limit are set, in which case a Response page is served.
 type Handler struct {
-	prof profiler.Profiler
+	prof     profiler.Profiler
+	verifier highlight.Verifier
+	linker   editor.Linker
+	blamer   gitblame.Blamer
 }
 
-// New requires non-nil profiler.
-func New(prof profiler.Profiler) Handler {
-	return Handler{prof: prof}
+// New requires non-nil profiler. If verifier is non-nil, frames not
+// matching local source code are marked. Frames are resolved to
+// absolute local paths by linker. If blamer is non-nil, project
+// frames contain the last change of their line.
+func New(prof profiler.Profiler, verifier highlight.Verifier, linker editor.Linker, blamer gitblame.Blamer) Handler {
+	return Handler{prof: prof, verifier: verifier, linker: linker, blamer: blamer}
+}
+
+// Response is a page of running goroutines.
+type Response struct {
+	paging.Page
+	// Next is the URL of the next page, if any.
+	Next       string               `json:"next,omitempty"`
+	Goroutines []profiler.Goroutine `json:"goroutines"`
 }
 
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, err := filter.Parse(r.URL.Query())
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
+	o, err := order.Parse(r.URL.Query())
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
+	page, err := paging.Parse(r.URL.Query(), 0)
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
 	running, err := h.prof.Goroutines()
 	if err != nil {
 		serve.Error(w, r, err)
 		return
 	}
-	serve.JSON(w, r, running)
+	running, _ = f.Filter(running)
+	o.Sort(running, group.ModeNone)
+	running = paging.Slice(&page, running)
+	if running == nil {
+		running = []profiler.Goroutine{}
+	}
+	h.linker.Resolve(running)
+	if h.verifier != nil {
+		err = highlight.VerifyGoroutines(r.Context(), running, h.verifier)
+		if err != nil {
+			serve.Error(w, r, err)
+			return
+		}
+	}
+	if h.blamer != nil {
+		err = gitblame.Goroutines(r.Context(), running, h.blamer)
+		if err != nil {
+			serve.Error(w, r, err)
+			return
+		}
+	}
+	if !paging.Requested(r.URL.Query()) {
+		serve.JSON(w, r, running)
+		return
+	}
+	res := Response{Page: page, Goroutines: running}
+	if page.HasNext() {
+		res.Next = paging.URL(r.URL.Path, r.URL.Query(), page.Next())
+	}
+	serve.JSON(w, r, res)
 }
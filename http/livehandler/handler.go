@@ -0,0 +1,213 @@
+// Package livehandler serves running goroutines in HTML format,
+// updated live over Server-Sent Events.
+package livehandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofu/gomon/http/filehandler"
+	"github.com/gofu/gomon/http/paging"
+	"github.com/gofu/gomon/http/router"
+	"github.com/gofu/gomon/http/serve"
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+	"github.com/gofu/gomon/profiler/group"
+	"github.com/gofu/gomon/profiler/order"
+	"github.com/gofu/gomon/profiler/poller"
+)
+
+// Handler serves a page that subscribes to its own URL with the
+// "Accept: text/event-stream" header, to receive an Update every
+// poll. Goroutines are filtered and sorted by the same query
+// parameters as the HTML page.
+type Handler struct {
+	poller *poller.Poller
+	routes router.Router
+}
+
+// New requires non-nil poller. Frames link to the routes source view.
+func New(p *poller.Poller, routes router.Router) *Handler {
+	return &Handler{poller: p, routes: routes}
+}
+
+// DefaultLimit is the number of shown goroutines, if not set.
+const DefaultLimit = 100
+
+// Request is the query of the page and its events.
+type Request struct {
+	filter.Filter
+	Order order.Order
+	Page  paging.Page
+}
+
+func ParseRequest(query url.Values) (Request, error) {
+	var req Request
+	var err error
+	if req.Filter, err = filter.Parse(query); err != nil {
+		return req, err
+	}
+	if req.Order, err = order.Parse(query); err != nil {
+		return req, err
+	}
+	req.Page, err = paging.Parse(query, DefaultLimit)
+	return req, err
+}
+
+// Row describes a goroutine shown in an Update.
+type Row struct {
+	ID int `json:"id"`
+	// IDURL is the URL of the goroutine page.
+	IDURL    string `json:"idUrl"`
+	Op       string `json:"op"`
+	Duration string `json:"duration,omitempty"`
+	// State is "appeared", "changed" or "disappeared" since the previous update.
+	State string `json:"state,omitempty"`
+	// PrevOp is the op before the goroutine changed.
+	PrevOp string `json:"prevOp,omitempty"`
+	// Func and File of the innermost project frame, or innermost frame.
+	Func string `json:"func"`
+	File string `json:"file"`
+	// URL of the frame's source view, if any.
+	URL string `json:"url,omitempty"`
+}
+
+// Update is the data of an event, sent every poll.
+type Update struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+	// Total number of filtered goroutines.
+	Total int `json:"total"`
+	// Appeared, Disappeared and Changed count filtered
+	// goroutines since the previous update.
+	Appeared    int `json:"appeared"`
+	Disappeared int `json:"disappeared"`
+	Changed     int `json:"changed"`
+	// Rows of the page, followed by disappeared goroutines.
+	Rows []Row `json:"rows"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := ParseRequest(r.URL.Query())
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
+	if r.Header.Get("Accept") == "text/event-stream" {
+		h.serveEvents(w, r, req)
+		return
+	}
+	serve.HTMLTemplate(w, r, tpl, Data{Interval: h.poller.Interval(), HTML: h.routes.HTML + "?" + r.URL.RawQuery})
+}
+
+func (h *Handler) serveEvents(w http.ResponseWriter, r *http.Request, req Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		serve.Error(w, r, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	snapshots, unsubscribe := h.poller.Subscribe()
+	defer unsubscribe()
+	// prev contains goroutines of the previous update, nil before the first one
+	var prev map[int]profiler.Goroutine
+	for {
+		var snap poller.Snapshot
+		select {
+		case <-r.Context().Done():
+			return
+		case snap = <-snapshots:
+		}
+		var upd Update
+		upd, prev = h.update(snap, req, prev)
+		data, err := json.Marshal(upd)
+		if err != nil {
+			serve.Error(w, r, err)
+			return
+		}
+		if _, err = fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// update returns the changes of snap since prev, and goroutines of snap.
+func (h *Handler) update(snap poller.Snapshot, req Request, prev map[int]profiler.Goroutine) (Update, map[int]profiler.Goroutine) {
+	upd := Update{Time: snap.Time, Rows: []Row{}}
+	if snap.Err != nil {
+		upd.Error = snap.Err.Error()
+		return upd, prev
+	}
+	running, _ := req.Filter.Filter(snap.Goroutines)
+	// snapshot goroutines are shared
+	running = append([]profiler.Goroutine(nil), running...)
+	req.Order.Sort(running, group.ModeNone)
+	upd.Total = len(running)
+	current := make(map[int]profiler.Goroutine, len(running))
+	for _, gr := range running {
+		current[gr.ID] = gr
+		if prev == nil {
+			continue
+		}
+		if old, ok := prev[gr.ID]; !ok {
+			upd.Appeared++
+		} else if old.Op != gr.Op {
+			upd.Changed++
+		}
+	}
+	page := req.Page
+	for _, gr := range paging.Slice(&page, running) {
+		row := h.row(gr)
+		if old, ok := prev[gr.ID]; prev != nil && !ok {
+			row.State = "appeared"
+		} else if ok && old.Op != gr.Op {
+			row.State, row.PrevOp = "changed", old.Op
+		}
+		upd.Rows = append(upd.Rows, row)
+	}
+	var gone []profiler.Goroutine
+	for id, gr := range prev {
+		if _, ok := current[id]; !ok {
+			gone = append(gone, gr)
+		}
+	}
+	upd.Disappeared = len(gone)
+	order.Order{Key: order.KeyID}.Sort(gone, group.ModeNone)
+	for _, gr := range paging.Slice(&paging.Page{Limit: req.Page.Limit}, gone) {
+		row := h.row(gr)
+		row.State = "disappeared"
+		upd.Rows = append(upd.Rows, row)
+	}
+	return upd, current
+}
+
+// row describes gr by its innermost project frame, or innermost frame.
+func (h *Handler) row(gr profiler.Goroutine) Row {
+	row := Row{ID: gr.ID, IDURL: h.routes.Goroutine + strconv.Itoa(gr.ID), Op: gr.Op}
+	if gr.Duration != 0 {
+		row.Duration = gr.Duration.String()
+	}
+	if len(gr.CallStack) == 0 {
+		return row
+	}
+	frame := gr.CallStack[len(gr.CallStack)-1]
+	for i := len(gr.CallStack) - 1; i >= 0; i-- {
+		if gr.CallStack[i].Root == profiler.RootTypeProject {
+			frame = gr.CallStack[i]
+			break
+		}
+	}
+	row.Func = frame.Package + "." + frame.Method
+	row.File = frame.File + ":" + strconv.Itoa(frame.Line)
+	if len(frame.File) != 0 && frame.Root != profiler.RootTypeCGo {
+		row.URL = filehandler.URL(h.routes.Source, frame.FileLine)
+	}
+	return row
+}
@@ -0,0 +1,21 @@
+package livehandler
+
+import (
+	_ "embed"
+	"html/template"
+	"time"
+)
+
+var (
+	//go:embed tpl.gohtml
+	tplData string
+	tpl     = template.Must(template.New("").Parse(tplData))
+)
+
+// Data of the live page.
+type Data struct {
+	// Interval between updates.
+	Interval time.Duration
+	// HTML is the URL of the same goroutines in the HTML page.
+	HTML string
+}
@@ -0,0 +1,146 @@
+// Package paging splits long lists into pages by offset and limit query parameters.
+package paging
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Query parameters parsed by Parse.
+const (
+	QueryOffset = "offset"
+	QueryLimit  = "limit"
+)
+
+// Limits are the suggested page sizes.
+var Limits = []int{10, 50, 100, 500, 1000}
+
+// Page of a list.
+type Page struct {
+	// Offset of the first item on the page.
+	Offset int `json:"offset"`
+	// Limit is the max number of items on the page, 0 is unlimited.
+	Limit int `json:"limit"`
+	// Total number of items in the list, set by Slice.
+	Total int `json:"total"`
+}
+
+// Requested reports whether offset or limit query parameters are set.
+func Requested(query url.Values) bool {
+	return query.Has(QueryOffset) || query.Has(QueryLimit)
+}
+
+// Parse reads offset and limit query parameters. If limit is not set,
+// it's defaultLimit.
+func Parse(query url.Values, defaultLimit int) (Page, error) {
+	p := Page{Limit: defaultLimit}
+	var err error
+	if offset := query.Get(QueryOffset); len(offset) != 0 {
+		p.Offset, err = strconv.Atoi(offset)
+		if err != nil {
+			return p, err
+		}
+		if p.Offset < 0 {
+			return p, fmt.Errorf("invalid negative offset %d", p.Offset)
+		}
+	}
+	if limit := query.Get(QueryLimit); len(limit) != 0 {
+		p.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return p, err
+		}
+		if p.Limit < 0 {
+			return p, fmt.Errorf("invalid negative limit %d", p.Limit)
+		}
+	}
+	return p, nil
+}
+
+// Slice returns items on page p, and sets p.Total to len(items).
+func Slice[T any](p *Page, items []T) []T {
+	p.Total = len(items)
+	if p.Empty() {
+		return nil
+	}
+	return items[p.Offset:p.End()]
+}
+
+// End returns the offset after the last item on the page.
+// It's Offset if the page is past the last item.
+func (p Page) End() int {
+	switch {
+	case p.Offset >= p.Total:
+		return p.Offset
+	case p.Limit == 0 || p.Offset+p.Limit > p.Total:
+		return p.Total
+	default:
+		return p.Offset + p.Limit
+	}
+}
+
+// Empty reports whether there are no items on the page.
+func (p Page) Empty() bool { return p.End() == p.Offset }
+
+// HasPrev reports whether there are items before the page.
+func (p Page) HasPrev() bool { return p.Offset > 0 }
+
+// HasNext reports whether there are items after the page.
+func (p Page) HasNext() bool { return p.End() < p.Total }
+
+// Prev returns the offset of the previous page, or the last page
+// if the page is past the last item.
+func (p Page) Prev() int {
+	switch {
+	case p.Offset < p.Limit:
+		return 0
+	case p.Offset > p.Last():
+		return p.Last()
+	default:
+		return p.Offset - p.Limit
+	}
+}
+
+// Next returns the offset of the next page.
+func (p Page) Next() int { return p.End() }
+
+// Last returns the offset of the last page.
+func (p Page) Last() int {
+	if p.Limit == 0 || p.Total == 0 {
+		return 0
+	}
+	return (p.Total - 1) / p.Limit * p.Limit
+}
+
+// Number returns the 1-based page number.
+func (p Page) Number() int {
+	if p.Limit == 0 {
+		return 1
+	}
+	return p.Offset/p.Limit + 1
+}
+
+// Pages returns the number of pages.
+func (p Page) Pages() int {
+	if p.Limit == 0 {
+		return 1
+	}
+	return p.Last()/p.Limit + 1
+}
+
+// URL returns path with query, and the offset parameter replaced.
+func URL(path string, query url.Values, offset int) string {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	if offset == 0 {
+		q.Del(QueryOffset)
+	} else {
+		q.Set(QueryOffset, strconv.Itoa(offset))
+	}
+	if len(q) == 0 {
+		return path
+	}
+	return path + "?" + q.Encode()
+}
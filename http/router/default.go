@@ -2,8 +2,14 @@ package router
 
 // Default application routes.
 var Default = Router{
-	Index: "/",
-	HTML:  "/html",
-	JSON:  "/json",
-	PProf: "/debug/pprof/",
+	Index:     "/",
+	HTML:      "/html",
+	JSON:      "/json",
+	Live:      "/live",
+	Goroutine: "/goroutine/",
+	Flame:     "/flame",
+	Summary:   "/summary",
+	Source:    "/source",
+	Cache:     "/cache",
+	PProf:     "/debug/pprof/",
 }
@@ -10,6 +10,18 @@ type Router struct {
 	HTML string
 	// JSON list of running goroutines.
 	JSON string
+	// Goroutine page prefix, followed by goroutine ID.
+	Goroutine string
+	// Live list of running goroutines, updated over Server-Sent Events.
+	Live string
+	// Flame graph of call stacks of running goroutines.
+	Flame string
+	// Summary of running goroutines, in HTML or JSON with a ".json" suffix.
+	Summary string
+	// Source file, highlighted in HTML.
+	Source string
+	// Cache statistics of highlighted source files, POST flushes the cache.
+	Cache string
 	// PProf debug info (by default /debug/pprof)
 	PProf string
 }
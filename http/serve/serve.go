@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -32,3 +33,22 @@ func Error(w http.ResponseWriter, r *http.Request, err error) {
 	log.Printf("HTTP %s %s error: %s", r.Method, r.URL, err)
 	_, _ = io.Copy(w, strings.NewReader(err.Error()))
 }
+
+// SameOrigin reports whether r was not sent cross-site by a browser, so
+// state-changing requests can't be forged by other pages. Requests
+// without Sec-Fetch-Site and Origin headers, eg. by curl, are allowed.
+func SameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
@@ -0,0 +1,112 @@
+// Package sourcehandler serves read-only .go source files of the running process.
+// It's meant to be mounted by the monitored process next to net/http/pprof,
+// so gomon can highlight call stacks using the source code baked next to the
+// binary (see highlight/remotefs):
+//
+//	http.Handle(sourcehandler.DefaultPath, sourcehandler.New(env.Env{
+//		Root:   "/src",
+//		GoRoot: runtime.GOROOT(),
+//	}))
+//
+// Only project files are served by default, see Handler.Allow.
+package sourcehandler
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofu/gomon/env"
+	"github.com/gofu/gomon/profiler"
+)
+
+// DefaultPath is the default URL path of the handler, next to /debug/pprof pages.
+const DefaultPath = "/debug/pprof/source"
+
+// Handler serves GET ?root=&file= requests, where root is a profiler.RootType,
+// and file is a .go file path relative to the root.
+type Handler struct {
+	// Env contains absolute local source roots. Roots with empty paths are not served.
+	Env env.Env
+	// Allow restricts served files per root, to the listed relative directory
+	// paths, eg. {profiler.RootTypeGoPath: {"pkg/mod/github.com/acme"}}, or ""
+	// for the whole root. Roots missing from Allow are not served. If nil,
+	// only RootTypeProject files are served.
+	Allow map[profiler.RootType][]string
+}
+
+// New returns Handler serving .go files in the env project root.
+func New(env env.Env) *Handler {
+	return &Handler{Env: env}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	root := profiler.RootType(r.URL.Query().Get("root"))
+	file := r.URL.Query().Get("file")
+	if !h.allowed(root, file) {
+		http.NotFound(w, r)
+		return
+	}
+	name, ok := h.resolve(root, file)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// allowed reports whether file from root can be served.
+func (h *Handler) allowed(root profiler.RootType, file string) bool {
+	if len(h.Env.RootPath(root)) == 0 || !fs.ValidPath(file) || path.Ext(file) != ".go" {
+		return false
+	}
+	if h.Allow == nil {
+		return root == profiler.RootTypeProject
+	}
+	for _, dir := range h.Allow[root] {
+		dir = strings.Trim(path.Clean("/"+dir), "/")
+		if len(dir) == 0 || strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the absolute path of file in root, with symlinks
+// evaluated, if it does not escape the root.
+func (h *Handler) resolve(root profiler.RootType, file string) (string, bool) {
+	dir, err := filepath.EvalSymlinks(h.Env.RootPath(root))
+	if err != nil {
+		return "", false
+	}
+	name, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return name, true
+}
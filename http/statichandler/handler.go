@@ -2,14 +2,28 @@
 package statichandler
 
 import (
+	"bytes"
 	_ "embed"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/style"
 )
 
-// FaviconURL is the default favicon URL requested by browsers.
-const FaviconURL = "/favicon.ico"
+const (
+	// FaviconURL is the default favicon URL requested by browsers.
+	FaviconURL = "/favicon.ico"
+	// ThemeURL serves the stylesheet of highlighted source code,
+	// for the theme selected by Theme.
+	ThemeURL = "/theme.css"
+	// ThemeCookie remembers the selected theme.
+	ThemeCookie = "theme"
+)
 
 // Handler serves static files.
 type Handler struct{}
@@ -17,12 +31,72 @@ type Handler struct{}
 //go:embed favicon.png
 var favicon string
 
+// stylesheets caches generated theme stylesheets by theme name.
+var stylesheets sync.Map
+
 func (Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.URL.Path {
 	case FaviconURL:
 		w.Header().Set("Content-Type", "image/png")
 		_, _ = io.Copy(w, strings.NewReader(favicon))
+	case ThemeURL:
+		css, err := stylesheet(Theme(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		w.Header().Set("Vary", "Cookie")
+		http.ServeContent(w, r, ThemeURL, time.Time{}, bytes.NewReader(css))
 	default:
 		http.NotFound(w, r)
 	}
 }
+
+// stylesheet returns the generated stylesheet of a theme.
+func stylesheet(theme string) ([]byte, error) {
+	if css, ok := stylesheets.Load(theme); ok {
+		return css.([]byte), nil
+	}
+	var buf bytes.Buffer
+	if err := highlight.WriteCSS(&buf, style.Get(theme)); err != nil {
+		return nil, err
+	}
+	stylesheets.Store(theme, buf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Theme returns the theme selected by the "theme" query parameter,
+// or ThemeCookie. Unknown themes are replaced by style.Default.
+func Theme(r *http.Request) string {
+	theme := r.URL.Query().Get("theme")
+	if len(theme) == 0 {
+		if c, err := r.Cookie(ThemeCookie); err == nil {
+			theme = c.Value
+		}
+	}
+	if style.Get(theme) == nil {
+		return style.Default
+	}
+	return theme
+}
+
+// StylesheetURL returns ThemeURL of a theme.
+func StylesheetURL(theme string) string {
+	return ThemeURL + "?" + url.Values{"theme": {theme}}.Encode()
+}
+
+// RememberTheme sets ThemeCookie if the theme was selected by
+// the "theme" query parameter.
+func RememberTheme(w http.ResponseWriter, r *http.Request, theme string) {
+	if len(r.URL.Query().Get("theme")) == 0 {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     ThemeCookie,
+		Value:    theme,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
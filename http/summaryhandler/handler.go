@@ -0,0 +1,77 @@
+// Package summaryhandler serves an overview of running goroutines
+// in HTML and JSON format.
+package summaryhandler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gofu/gomon/http/htmlhandler"
+	"github.com/gofu/gomon/http/router"
+	"github.com/gofu/gomon/http/serve"
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+	"github.com/gofu/gomon/profiler/history"
+	"github.com/gofu/gomon/profiler/summary"
+)
+
+const (
+	// TopLimit is the number of listed packages and project lines.
+	TopLimit = 10
+	// TimelineLimit is the number of charted ops and stack groups.
+	TimelineLimit = 5
+)
+
+// Handler serves the summary of running goroutines at routes.Summary
+// in HTML, or routes.Summary+".json" in JSON. Goroutines are filtered
+// by the same query parameters as the HTML page, and counts link to
+// the HTML page narrowed to the counted goroutines.
+type Handler struct {
+	prof   profiler.Profiler
+	routes router.Router
+	hist   *history.History
+}
+
+// New requires non-nil profiler. If hist is non-nil, the HTML page
+// charts its counts of all goroutines over time.
+func New(prof profiler.Profiler, routes router.Router, hist *history.History) *Handler {
+	return &Handler{prof: prof, routes: routes, hist: hist}
+}
+
+// Response is the summary of filtered goroutines.
+type Response struct {
+	summary.Summary
+	// Skipped number of goroutines, not matching the filter.
+	Skipped int `json:"skipped"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := h.Execute(r.URL.Query())
+	if err != nil {
+		serve.Error(w, r, err)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, ".json") {
+		serve.JSON(w, r, data.Response)
+		return
+	}
+	serve.HTMLTemplate(w, r, tpl, data)
+}
+
+// Execute returns the summary of goroutines matching the query filter.
+// Blocked durations are bucketed by htmlhandler.Durations.
+func (h *Handler) Execute(query url.Values) (Data, error) {
+	data := Data{routes: h.routes, query: query, hist: h.hist}
+	f, err := filter.Parse(query)
+	if err != nil {
+		return data, err
+	}
+	running, err := h.prof.Goroutines()
+	if err != nil {
+		return data, err
+	}
+	running, data.Skipped = f.Filter(running)
+	data.Summary = summary.Goroutines(running, htmlhandler.Durations, TopLimit)
+	return data, nil
+}
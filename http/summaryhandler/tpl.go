@@ -0,0 +1,149 @@
+package summaryhandler
+
+import (
+	_ "embed"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofu/gomon/http/paging"
+	"github.com/gofu/gomon/http/router"
+	"github.com/gofu/gomon/http/timeline"
+	"github.com/gofu/gomon/profiler/filter"
+	"github.com/gofu/gomon/profiler/history"
+)
+
+var (
+	//go:embed tpl.gohtml
+	tplData string
+	tpl     = template.Must(template.New("").Funcs(template.FuncMap{
+		"percent": func(f float64) string { return strconv.FormatFloat(f*100, 'f', 2, 64) + "%" },
+	}).Parse(tplData))
+)
+
+// Data of the summary page.
+type Data struct {
+	Response
+	routes router.Router
+	query  url.Values
+	hist   *history.History
+}
+
+// Timeline returns line charts of all goroutines over time,
+// or nil without history.
+func (d Data) Timeline() []timeline.Chart {
+	if d.hist == nil {
+		return nil
+	}
+	points := d.hist.Points()
+	return []timeline.Chart{
+		timeline.Lines("Total", points, []history.Series{history.Totals(points)}),
+		timeline.Lines("Top ops", points, history.Ops(points, TimelineLimit)),
+		timeline.Lines("Top stack groups", points, history.Groups(points, TimelineLimit)),
+	}
+}
+
+// Bar of a chart.
+type Bar struct {
+	Label string
+	Count int
+	// Width relative to the longest bar of the chart.
+	Width float64
+	// URL of the HTML page, narrowed to the counted goroutines.
+	URL string
+}
+
+// Chart of goroutine counts.
+type Chart struct {
+	Title string
+	Bars  []Bar
+}
+
+// HTMLURL returns the URL of the HTML page with the same filter.
+func (d Data) HTMLURL() string { return paging.URL(d.routes.HTML, d.query, 0) }
+
+// FlameURL returns the URL of the flame graph with the same filter.
+func (d Data) FlameURL() string { return paging.URL(d.routes.Flame, d.query, 0) }
+
+// JSONURL returns the URL of the summary in JSON.
+func (d Data) JSONURL() string { return paging.URL(d.routes.Summary+".json", d.query, 0) }
+
+// Charts returns bar charts of the summary.
+func (d Data) Charts() []Chart {
+	ops := Chart{Title: "Ops"}
+	for _, c := range d.Ops {
+		ops.Bars = append(ops.Bars, d.bar(c.Name, c.Count, string(filter.FieldOpIs)+":"+c.Name))
+	}
+	durations := Chart{Title: "Blocked duration"}
+	for _, b := range d.Durations {
+		q := url.Values{}
+		for k, v := range d.query {
+			q[k] = v
+		}
+		q.Del("min")
+		q.Del("max")
+		var label string
+		switch {
+		case b.Min == 0:
+			label = "< " + shortDuration(b.Max)
+		case b.Max == 0:
+			label = "≥ " + shortDuration(b.Min)
+		default:
+			label = shortDuration(b.Min) + " - " + shortDuration(b.Max)
+		}
+		if b.Min != 0 {
+			q.Set("min", b.Min.String())
+		}
+		if b.Max != 0 {
+			// buckets exclude Max, while the filter includes it
+			q.Set("max", (b.Max - time.Nanosecond).String())
+		}
+		durations.Bars = append(durations.Bars, Bar{Label: label, Count: b.Count, URL: paging.URL(d.routes.HTML, q, 0)})
+	}
+	packages := Chart{Title: "Top packages"}
+	for _, c := range d.Packages {
+		packages.Bars = append(packages.Bars, d.bar(c.Name, c.Count, string(filter.FieldRegexp)+":^"+regexp.QuoteMeta(c.Name)+`\.`))
+	}
+	leaves := Chart{Title: "Top project lines"}
+	for _, l := range d.Leaves {
+		label := l.Func + " " + l.File + ":" + strconv.Itoa(l.Line)
+		leaves.Bars = append(leaves.Bars, d.bar(label, l.Count, string(filter.FieldRegexp)+":"+filter.FrameRegexp(l.Func, l.File, l.Line)))
+	}
+	roots := Chart{Title: "Root types"}
+	for _, c := range d.Roots {
+		roots.Bars = append(roots.Bars, d.bar(c.Name, c.Count, string(filter.FieldRoot)+":"+c.Name))
+	}
+	charts := []Chart{ops, durations, packages, leaves, roots}
+	for _, chart := range charts {
+		var max int
+		for _, b := range chart.Bars {
+			if b.Count > max {
+				max = b.Count
+			}
+		}
+		for i := range chart.Bars {
+			chart.Bars[i].Width = float64(chart.Bars[i].Count) / float64(max)
+		}
+	}
+	return charts
+}
+
+// bar links to goroutines matching condition.
+func (d Data) bar(label string, count int, condition string) Bar {
+	return Bar{Label: label, Count: count, URL: paging.URL(d.routes.HTML, filter.Narrow(d.query, condition), 0)}
+}
+
+// shortDuration formats d without zero minutes and seconds, eg. "1h" instead of "1h0m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
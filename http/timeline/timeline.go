@@ -0,0 +1,138 @@
+// Package timeline renders goroutine history as SVG line charts.
+package timeline
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofu/gomon/profiler/history"
+)
+
+var (
+	//go:embed tpl.gohtml
+	tplData string
+	tpl     = template.Must(template.New("").Funcs(template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+	}).Parse(tplData))
+)
+
+// colors of lines, repeated if there are more series.
+var colors = []string{"#c28e55", "#87ceeb", "#db79ff", "#ff8779", "#8fd18b", "#ffcc00", "#b0b0b0", "#5fa8a0"}
+
+// Chart of series of counts over time.
+type Chart struct {
+	Title string
+	// Width and Height of the plot area in pixels.
+	Width, Height int
+	// Sparkline charts omit the legend and axis labels.
+	Sparkline bool
+	// Points that series are counted from, oldest first. Failed polls,
+	// and gaps of series, break the lines.
+	Points []history.Point
+	Series []history.Series
+}
+
+// Sparkline returns a small chart of the total number of goroutines.
+func Sparkline(points []history.Point) Chart {
+	return Chart{Width: 200, Height: 24, Sparkline: true, Points: points, Series: []history.Series{history.Totals(points)}}
+}
+
+// Lines returns a chart of series.
+func Lines(title string, points []history.Point, series []history.Series) Chart {
+	return Chart{Title: title, Width: 800, Height: 160, Points: points, Series: series}
+}
+
+// Last returns the latest count of the first series, skipping failed polls and gaps.
+func (c Chart) Last() int {
+	if len(c.Series) == 0 {
+		return 0
+	}
+	for i := len(c.Points) - 1; i >= 0; i-- {
+		if len(c.Points[i].Error) == 0 && !c.Series[0].Gap(i) {
+			return c.Series[0].Values[i]
+		}
+	}
+	return 0
+}
+
+// line is a rendered history.Series.
+type line struct {
+	Name  string
+	Color string
+	// Segments are SVG polyline points, split by failed polls and gaps.
+	Segments []string
+	// Last is the latest count.
+	Last int
+}
+
+// view of a Chart, passed to the template.
+type view struct {
+	Chart
+	Lines    []line
+	Max      int
+	From, To string
+}
+
+// SVG returns the chart, or nothing if there are no points.
+func (c Chart) SVG() (template.HTML, error) {
+	if len(c.Points) == 0 {
+		return "", nil
+	}
+	v := view{Chart: c}
+	for _, s := range c.Series {
+		for _, value := range s.Values {
+			if value > v.Max {
+				v.Max = value
+			}
+		}
+	}
+	from, to := c.Points[0].Time, c.Points[len(c.Points)-1].Time
+	v.From, v.To = from.Format(time.Kitchen), to.Format(time.Kitchen)
+	span := to.Sub(from)
+	for i, s := range c.Series {
+		l := line{Name: s.Name, Color: colors[i%len(colors)]}
+		var segment []string
+		for j, value := range s.Values {
+			p := c.Points[j]
+			if len(p.Error) != 0 || s.Gap(j) {
+				l.Segments = appendSegment(l.Segments, segment)
+				segment = nil
+				continue
+			}
+			l.Last = value
+			x := float64(c.Width)
+			if span > 0 {
+				x = float64(p.Time.Sub(from)) / float64(span) * float64(c.Width)
+			}
+			y := float64(c.Height)
+			if v.Max > 0 {
+				y -= float64(value) / float64(v.Max) * float64(c.Height)
+			}
+			segment = append(segment, strconv.FormatFloat(x, 'f', 1, 64)+","+strconv.FormatFloat(y, 'f', 1, 64))
+		}
+		l.Segments = appendSegment(l.Segments, segment)
+		v.Lines = append(v.Lines, l)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, v); err != nil {
+		return "", err
+	}
+	// the template is trusted, and its data is escaped
+	return template.HTML(buf.String()), nil
+}
+
+// appendSegment appends non-empty segment points. A single point
+// is repeated, to be drawn as a dot by round line caps.
+func appendSegment(segments []string, segment []string) []string {
+	switch len(segment) {
+	case 0:
+		return segments
+	case 1:
+		segment = append(segment, segment[0])
+	}
+	return append(segments, strings.Join(segment, " "))
+}
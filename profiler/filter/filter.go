@@ -0,0 +1,275 @@
+// Package filter selects goroutines by blocked duration and call stack conditions.
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofu/gomon/profiler"
+)
+
+// Field of a goroutine that a Condition matches.
+type Field string
+
+const (
+	// FieldPackage matches frames of a package or its subpackages, eg. "net/http".
+	FieldPackage Field = "package"
+	// FieldFunc matches frames whose "package.Method" contains the value, eg. "(*Server).Serve".
+	FieldFunc Field = "func"
+	// FieldFile matches frames whose file path contains the value, eg. "server.go".
+	FieldFile Field = "file"
+	// FieldOp matches goroutines whose op contains the value, eg. "chan receive".
+	FieldOp Field = "op"
+	// FieldOpIs matches goroutines whose op equals the value, eg. "chan receive"
+	// but not "chan receive (nil chan)".
+	FieldOpIs Field = "opis"
+	// FieldRoot matches frames of a profiler.RootType, eg. "PROJECT".
+	FieldRoot Field = "root"
+	// FieldID matches goroutine IDs in a range, eg. "10-20", "10-", "-20" or "15".
+	FieldID Field = "id"
+	// FieldDepth matches call stack depth in a range, same as FieldID.
+	FieldDepth Field = "depth"
+	// FieldRegexp matches frames by a regular expression on "package.Method file:line".
+	FieldRegexp Field = "regexp"
+)
+
+// Fields lists all fields that conditions can match.
+var Fields = []Field{FieldPackage, FieldFunc, FieldFile, FieldOp, FieldOpIs, FieldRoot, FieldID, FieldDepth, FieldRegexp}
+
+// Condition matches a goroutine field.
+type Condition struct {
+	Field Field
+	Value string
+	// Exclude negates the condition.
+	Exclude bool
+	// re is the compiled FieldRegexp Value
+	re *regexp.Regexp
+	// min and max are inclusive FieldID/FieldDepth bounds, max -1 is unbounded
+	min, max int
+}
+
+// ParseCondition parses a "field:value" condition.
+func ParseCondition(s string, exclude bool) (Condition, error) {
+	field, value, ok := strings.Cut(s, ":")
+	c := Condition{Field: Field(strings.TrimSpace(field)), Value: value, Exclude: exclude}
+	if !ok || len(value) == 0 {
+		return c, fmt.Errorf("invalid filter %q, expected field:value", s)
+	}
+	var err error
+	switch c.Field {
+	case FieldPackage, FieldFunc, FieldFile, FieldOp, FieldOpIs, FieldRoot:
+	case FieldID, FieldDepth:
+		c.min, c.max, err = parseRange(value)
+	case FieldRegexp:
+		c.re, err = regexp.Compile(value)
+	default:
+		err = fmt.Errorf("unknown filter field %q", field)
+	}
+	if err != nil {
+		return c, fmt.Errorf("filter %q: %w", s, err)
+	}
+	return c, nil
+}
+
+// parseRange parses "min-max", "min-", "-max" or "value" into inclusive bounds.
+func parseRange(s string) (min, max int, err error) {
+	from, to, isRange := strings.Cut(s, "-")
+	if !isRange {
+		to = from
+	}
+	max = -1
+	if len(from) != 0 {
+		if min, err = strconv.Atoi(from); err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(to) != 0 {
+		if max, err = strconv.Atoi(to); err != nil {
+			return 0, 0, err
+		}
+	}
+	return min, max, nil
+}
+
+// String returns the condition in ParseCondition format.
+func (c Condition) String() string { return string(c.Field) + ":" + c.Value }
+
+// Match reports whether gr matches the condition, regardless of Exclude.
+func (c Condition) Match(gr profiler.Goroutine) bool {
+	switch c.Field {
+	case FieldOp:
+		return strings.Contains(gr.Op, c.Value)
+	case FieldOpIs:
+		return gr.Op == c.Value
+	case FieldID:
+		return c.inRange(gr.ID)
+	case FieldDepth:
+		return c.inRange(len(gr.CallStack))
+	}
+	for _, s := range gr.CallStack {
+		if c.matchFrame(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) matchFrame(s profiler.CallStack) bool {
+	switch c.Field {
+	case FieldPackage:
+		return s.Package == c.Value || strings.HasPrefix(s.Package, c.Value+"/")
+	case FieldFunc:
+		return strings.Contains(s.Package+"."+s.Method, c.Value)
+	case FieldFile:
+		return strings.Contains(s.File, c.Value)
+	case FieldRoot:
+		return strings.EqualFold(string(s.Root), c.Value)
+	case FieldRegexp:
+		return c.re.MatchString(s.Package + "." + s.Method + " " + s.File + ":" + strconv.Itoa(s.Line))
+	default:
+		return false
+	}
+}
+
+func (c Condition) inRange(n int) bool {
+	return n >= c.min && (c.max == -1 || n <= c.max)
+}
+
+// Func returns "package.Method" of s, without the "created by" goroutine
+// suffix, that differs between frames of the same function.
+func Func(s profiler.CallStack) string {
+	method, _ := profiler.CutCreatedIn(s.Method)
+	return s.Package + "." + method
+}
+
+// FrameRegexp returns a FieldRegexp value matching frames of function fn,
+// as returned by Func, at file:line.
+func FrameRegexp(fn, file string, line int) string {
+	createdIn := "(?:" + regexp.QuoteMeta(profiler.CreatedInPrefix) + `\d+)?`
+	return "^" + regexp.QuoteMeta(fn) + createdIn + " " + regexp.QuoteMeta(file) + ":" + strconv.Itoa(line) + "$"
+}
+
+// Filter selects goroutines blocked for a duration range, that match
+// all (or any) Conditions.
+type Filter struct {
+	// MinDuration duration of goroutines to show.
+	MinDuration time.Duration
+	// MaxDuration duration of goroutines to show.
+	MaxDuration time.Duration
+	// Conditions that goroutines must match.
+	Conditions []Condition
+	// Any requires goroutines to match at least one condition, instead of all.
+	Any bool
+}
+
+// Query parameters parsed by Parse.
+const (
+	// QueryInclude conditions, eg. "include=package:net/http".
+	QueryInclude = "include"
+	// QueryExclude conditions, eg. "exclude=op:IO wait".
+	QueryExclude = "exclude"
+	// QueryMatch is MatchAll or MatchAny.
+	QueryMatch = "match"
+	// MatchAll combines conditions with AND.
+	MatchAll = "all"
+	// MatchAny combines conditions with OR.
+	MatchAny = "any"
+)
+
+// Parse reads the filter from min and max durations, include and exclude
+// conditions, and the match query parameters. Empty conditions are ignored.
+func Parse(query url.Values) (Filter, error) {
+	var f Filter
+	var err error
+	if min := query.Get("min"); len(min) != 0 {
+		f.MinDuration, err = time.ParseDuration(min)
+		if err != nil {
+			return f, err
+		}
+	}
+	if max := query.Get("max"); len(max) != 0 {
+		f.MaxDuration, err = time.ParseDuration(max)
+		if err != nil {
+			return f, err
+		}
+	}
+	for _, param := range []string{QueryInclude, QueryExclude} {
+		for _, s := range query[param] {
+			if len(strings.TrimSpace(s)) == 0 {
+				continue
+			}
+			c, err := ParseCondition(s, param == QueryExclude)
+			if err != nil {
+				return f, err
+			}
+			f.Conditions = append(f.Conditions, c)
+		}
+	}
+	switch match := query.Get(QueryMatch); match {
+	case "", MatchAll:
+	case MatchAny:
+		f.Any = true
+	default:
+		return f, fmt.Errorf("invalid match %q, expected %s or %s", match, MatchAll, MatchAny)
+	}
+	return f, nil
+}
+
+// Narrow returns a copy of query, with the include condition added.
+// Conditions matched by any are removed, as the added condition
+// would extend them instead.
+func Narrow(query url.Values, condition string) url.Values {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	if q.Get(QueryMatch) == MatchAny {
+		q.Del(QueryInclude)
+		q.Del(QueryExclude)
+		q.Del(QueryMatch)
+	}
+	q.Add(QueryInclude, condition)
+	return q
+}
+
+func (f Filter) IncludeAll() bool {
+	return f.MinDuration == 0 && f.MaxDuration == 0 && len(f.Conditions) == 0
+}
+
+func (f Filter) Include(gr profiler.Goroutine) bool {
+	if f.MinDuration != 0 && gr.Duration < f.MinDuration {
+		return false
+	}
+	if f.MaxDuration != 0 && gr.Duration > f.MaxDuration {
+		return false
+	}
+	if len(f.Conditions) == 0 {
+		return true
+	}
+	for _, c := range f.Conditions {
+		if matched := c.Match(gr) != c.Exclude; matched == f.Any {
+			return matched
+		}
+	}
+	return !f.Any
+}
+
+func (f Filter) Filter(gs []profiler.Goroutine) ([]profiler.Goroutine, int) {
+	var skipped int
+	if f.IncludeAll() {
+		return gs, 0
+	}
+	var filtered []profiler.Goroutine
+	for _, g := range gs {
+		if !f.Include(g) {
+			skipped++
+			continue
+		}
+		filtered = append(filtered, g)
+	}
+	return filtered, skipped
+}
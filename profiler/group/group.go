@@ -0,0 +1,167 @@
+// Package group buckets goroutines with identical call stacks.
+package group
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofu/gomon/profiler"
+)
+
+// Mode defines which call stack frame details must match for
+// goroutines to be grouped together.
+type Mode string
+
+const (
+	// ModeNone does not group goroutines.
+	ModeNone Mode = ""
+	// ModeExact groups goroutines with the same functions,
+	// call arguments and lines.
+	ModeExact Mode = "exact"
+	// ModeIgnoreArgs groups goroutines with the same functions
+	// and lines, regardless of call arguments.
+	ModeIgnoreArgs Mode = "args"
+	// ModeIgnoreLine groups goroutines with the same functions,
+	// regardless of call arguments and lines within functions.
+	ModeIgnoreLine Mode = "line"
+)
+
+// Modes lists all grouping modes, except ModeNone.
+var Modes = []Mode{ModeExact, ModeIgnoreArgs, ModeIgnoreLine}
+
+// ParseMode returns a valid Mode, or an error.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeNone, ModeExact, ModeIgnoreArgs, ModeIgnoreLine:
+		return mode, nil
+	default:
+		return ModeNone, fmt.Errorf("invalid group mode %q", s)
+	}
+}
+
+// Label returns a human-readable name of mode.
+func (m Mode) Label() string {
+	switch m {
+	case ModeNone:
+		return "Off"
+	case ModeExact:
+		return "Exact stack"
+	case ModeIgnoreArgs:
+		return "Ignore args"
+	case ModeIgnoreLine:
+		return "Ignore args and lines"
+	default:
+		return string(m)
+	}
+}
+
+// OpCount is the number of goroutines in a group blocked by Op.
+type OpCount struct {
+	Op    string `json:"op"`
+	Count int    `json:"count"`
+}
+
+// Group of goroutines sharing a call stack signature.
+type Group struct {
+	// Goroutine is the first goroutine of the group,
+	// and its CallStack represents the whole group.
+	profiler.Goroutine
+	// IDs of all goroutines in the group, including Goroutine.
+	IDs []int `json:"ids"`
+	// Ops of goroutines in the group, most common first.
+	Ops []OpCount `json:"ops"`
+	// MinDuration and MaxDuration that goroutines have been blocked for.
+	MinDuration time.Duration `json:"minDuration,omitempty"`
+	MaxDuration time.Duration `json:"maxDuration,omitempty"`
+}
+
+// Count returns the number of goroutines in the group.
+func (g Group) Count() int { return len(g.IDs) }
+
+// Goroutines groups goroutines by call stack signature defined by mode.
+// Groups are ordered by their first goroutine's position in goroutines.
+// ModeNone returns a group for every goroutine.
+func Goroutines(goroutines []profiler.Goroutine, mode Mode) []Group {
+	var groups []Group
+	index := map[string]int{}
+	for _, gr := range goroutines {
+		key := Signature(gr, mode)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{
+				Goroutine:   gr,
+				MinDuration: gr.Duration,
+				MaxDuration: gr.Duration,
+			})
+		}
+		g := &groups[i]
+		g.IDs = append(g.IDs, gr.ID)
+		g.Ops = addOp(g.Ops, gr.Op)
+		if gr.Duration < g.MinDuration {
+			g.MinDuration = gr.Duration
+		}
+		if gr.Duration > g.MaxDuration {
+			g.MaxDuration = gr.Duration
+		}
+	}
+	for i := range groups {
+		ops := groups[i].Ops
+		sort.SliceStable(ops, func(i, j int) bool { return ops[i].Count > ops[j].Count })
+	}
+	return groups
+}
+
+// Representatives returns the first goroutine of every group. Their call
+// stacks share memory with the groups, so highlighting them fills groups.
+func Representatives(groups []Group) []profiler.Goroutine {
+	goroutines := make([]profiler.Goroutine, len(groups))
+	for i, g := range groups {
+		goroutines[i] = g.Goroutine
+	}
+	return goroutines
+}
+
+// Signature returns the call stack details of gr matched by mode.
+// Goroutines with equal signatures belong to the same group.
+// ModeNone returns a unique signature for every goroutine ID.
+func Signature(gr profiler.Goroutine, mode Mode) string {
+	if mode == ModeNone {
+		return strconv.Itoa(gr.ID)
+	}
+	var w strings.Builder
+	for _, s := range gr.CallStack {
+		w.WriteString(s.Package)
+		w.WriteByte('.')
+		w.WriteString(s.Method)
+		w.WriteByte(0)
+		w.WriteString(string(s.Root))
+		w.WriteByte(0)
+		w.WriteString(s.File)
+		w.WriteByte(0)
+		if mode != ModeIgnoreLine {
+			w.WriteString(strconv.Itoa(s.Line))
+			w.WriteByte(0)
+		}
+		if mode == ModeExact {
+			w.WriteString(s.Args)
+			w.WriteByte(0)
+		}
+		w.WriteByte('\n')
+	}
+	return w.String()
+}
+
+func addOp(ops []OpCount, op string) []OpCount {
+	for i := range ops {
+		if ops[i].Op == op {
+			ops[i].Count++
+			return ops
+		}
+	}
+	return append(ops, OpCount{Op: op, Count: 1})
+}
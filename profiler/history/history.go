@@ -0,0 +1,223 @@
+// Package history keeps counts of goroutines polled over time,
+// in a fixed-size in-memory ring buffer.
+package history
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+	"github.com/gofu/gomon/profiler/group"
+	"github.com/gofu/gomon/profiler/poller"
+)
+
+const (
+	// DefaultInterval between recorded polls, if not set.
+	DefaultInterval = 10 * time.Second
+	// DefaultRetention of recorded polls, if not set in New.
+	DefaultRetention = 6 * time.Hour
+	// TopGroups is the max number of stack groups counted per Point.
+	TopGroups = 20
+)
+
+// Point aggregates goroutines of a single poll.
+type Point struct {
+	Time time.Time `json:"time"`
+	// Error is set if polling failed, and counts are empty.
+	Error string `json:"error,omitempty"`
+	// Total number of goroutines.
+	Total int `json:"total"`
+	// Ops counts goroutines by op, eg. "chan receive".
+	Ops map[string]int `json:"ops"`
+	// Groups counts goroutines of the TopGroups largest groups with the
+	// same call stack, regardless of arguments. Groups are named by their
+	// innermost project frame, or innermost frame, eg. "main.worker main.go:19".
+	// Counts of groups with the same name are summed.
+	Groups map[string]int `json:"groups"`
+}
+
+// NewPoint returns the aggregates of snap.
+func NewPoint(snap poller.Snapshot) Point {
+	p := Point{Time: snap.Time}
+	if snap.Err != nil {
+		p.Error = snap.Err.Error()
+		return p
+	}
+	p.Total = len(snap.Goroutines)
+	p.Ops = map[string]int{}
+	for _, gr := range snap.Goroutines {
+		p.Ops[gr.Op]++
+	}
+	groups := group.Goroutines(snap.Goroutines, group.ModeIgnoreArgs)
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Count() > groups[j].Count() })
+	if len(groups) > TopGroups {
+		groups = groups[:TopGroups]
+	}
+	p.Groups = map[string]int{}
+	for _, g := range groups {
+		p.Groups[groupName(g.Goroutine)] += g.Count()
+	}
+	return p
+}
+
+// groupName returns the innermost project frame of gr, or innermost frame.
+func groupName(gr profiler.Goroutine) string {
+	if len(gr.CallStack) == 0 {
+		return gr.Op
+	}
+	frame := gr.CallStack[len(gr.CallStack)-1]
+	for i := len(gr.CallStack) - 1; i >= 0; i-- {
+		if gr.CallStack[i].Root == profiler.RootTypeProject {
+			frame = gr.CallStack[i]
+			break
+		}
+	}
+	return filter.Func(frame) + " " + frame.File + ":" + strconv.Itoa(frame.Line)
+}
+
+// History is a ring buffer of the latest points. It's safe for concurrent use.
+type History struct {
+	interval time.Duration
+	mu       sync.Mutex
+	points   []Point
+	// next is the index of the oldest point, once points is full
+	next int
+}
+
+// New returns a history keeping points polled every interval, for retention.
+// Zero values default to DefaultInterval and DefaultRetention.
+func New(interval, retention time.Duration) *History {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	size := int(retention / interval)
+	if size < 1 {
+		size = 1
+	}
+	return &History{interval: interval, points: make([]Point, 0, size)}
+}
+
+// Interval returns the duration between recorded polls.
+func (h *History) Interval() time.Duration { return h.interval }
+
+// Add records p, replacing the oldest point if the history is full.
+func (h *History) Add(p Point) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.points) < cap(h.points) {
+		h.points = append(h.points, p)
+		return
+	}
+	h.points[h.next] = p
+	h.next = (h.next + 1) % len(h.points)
+}
+
+// Points returns a copy of recorded points, oldest first.
+func (h *History) Points() []Point {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	points := make([]Point, 0, len(h.points))
+	points = append(points, h.points[h.next:]...)
+	return append(points, h.points[:h.next]...)
+}
+
+// Record adds points of goroutines polled by prof every Interval,
+// until ctx is done.
+func (h *History) Record(ctx context.Context, prof profiler.Profiler) {
+	snapshots, unsubscribe := poller.New(prof, h.interval).Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snap := <-snapshots:
+			h.Add(NewPoint(snap))
+		}
+	}
+}
+
+// Series of counts, one for every point.
+type Series struct {
+	Name   string `json:"name"`
+	Values []int  `json:"values"`
+	// Gaps marks points where the count is not known, if any.
+	Gaps []bool `json:"gaps,omitempty"`
+}
+
+// Gap reports whether the count at point i is not known.
+func (s Series) Gap(i int) bool {
+	return i < len(s.Gaps) && s.Gaps[i]
+}
+
+// Totals returns the total number of goroutines of points.
+func Totals(points []Point) Series {
+	s := Series{Name: "total", Values: make([]int, len(points))}
+	for i, p := range points {
+		s.Values[i] = p.Total
+	}
+	return s
+}
+
+// Ops returns counts of the n ops with the most goroutines
+// at any point, all if n is 0.
+func Ops(points []Point, n int) []Series {
+	return top(points, n, func(p Point) map[string]int { return p.Ops })
+}
+
+// Groups returns counts of the n stack groups with the most
+// goroutines at any point, all if n is 0. Groups missing from
+// a point, because they weren't in its TopGroups, are gaps.
+func Groups(points []Point, n int) []Series {
+	series := top(points, n, func(p Point) map[string]int { return p.Groups })
+	for i := range series {
+		for j, p := range points {
+			if _, ok := p.Groups[series[i].Name]; ok || len(p.Error) != 0 {
+				continue
+			}
+			if series[i].Gaps == nil {
+				series[i].Gaps = make([]bool, len(points))
+			}
+			series[i].Gaps[j] = true
+		}
+	}
+	return series
+}
+
+func top(points []Point, n int, counts func(Point) map[string]int) []Series {
+	peaks := map[string]int{}
+	for _, p := range points {
+		for name, count := range counts(p) {
+			if count > peaks[name] {
+				peaks[name] = count
+			}
+		}
+	}
+	names := make([]string, 0, len(peaks))
+	for name := range peaks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if peaks[names[i]] != peaks[names[j]] {
+			return peaks[names[i]] > peaks[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if n != 0 && len(names) > n {
+		names = names[:n]
+	}
+	series := make([]Series, len(names))
+	for i, name := range names {
+		series[i] = Series{Name: name, Values: make([]int, len(points))}
+		for j, p := range points {
+			series[i].Values[j] = counts(p)[name]
+		}
+	}
+	return series
+}
@@ -76,8 +76,8 @@ func (p Goroutine) ParseGoroutine(data string) (profiler.Goroutine, error) {
 		}
 		var matches []string
 		const createdByPrefix = "created by "
-		if strings.HasPrefix(s.Text(), createdByPrefix) {
-			// main goroutine
+		createdBy := strings.HasPrefix(s.Text(), createdByPrefix)
+		if createdBy {
 			matches = []string{"", strings.TrimPrefix(s.Text(), createdByPrefix), ""}
 		} else {
 			matches = goroutineStackRegexp.FindStringSubmatch(s.Text())
@@ -93,6 +93,7 @@ func (p Goroutine) ParseGoroutine(data string) (profiler.Goroutine, error) {
 			pkg, method = pkg+method[:cut], method[cut+1:]
 		}
 		stack := profiler.CallStack{
+			Caller:  createdBy,
 			Package: pkg,
 			Method:  method,
 			Args:    matches[2],
@@ -2,20 +2,26 @@
 package httpprofiler
 
 import (
+	"context"
 	"fmt"
-	"golang.org/x/exp/slices"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gofu/gomon/env"
 	"github.com/gofu/gomon/profiler"
 	"github.com/gofu/gomon/profiler/httpparser"
 )
 
+// Timeout of requests to remote pages, including reading large goroutine dumps.
+const Timeout = time.Minute
+
 // Profiler parses running goroutines from remote /debug/pprof/ pages.
 type Profiler struct {
 	url    string
 	parser httpparser.Goroutine
+	client *http.Client
 }
 
 // New expects pprofURL to be a default /debug/pprof/ page.
@@ -29,29 +35,46 @@ func New(pprofURL string, env env.Env) *Profiler {
 	return &Profiler{
 		url:    pprofURL,
 		parser: httpparser.Goroutine{Env: env.Normalized()},
+		client: &http.Client{Timeout: Timeout},
 	}
 }
 
 // Source returns the full remote /debug/pprof URL.
 func (s *Profiler) Source() string { return s.url }
 
-// Goroutines parses running goroutines from remote URL.
+// Cmdline returns the remote process command-line arguments.
+func (s *Profiler) Cmdline(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/cmdline", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("read %s/cmdline: unexpected status %s", s.url, res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s/cmdline response: %w", s.url, err)
+	}
+	return strings.Split(string(data), "\x00"), nil
+}
+
+// Goroutines parses running goroutines from remote URL, in the order of
+// the remote profile.
 func (s *Profiler) Goroutines() ([]profiler.Goroutine, error) {
 	uri := s.url + "/goroutine?debug=2"
-	res, err := http.Get(uri)
+	res, err := s.client.Get(uri)
 	if err != nil {
 		return nil, err
 	}
 	running, err := s.parser.Parse(res.Body)
 	_ = res.Body.Close()
 	if err != nil {
-		return nil, fmt.Errorf("read %s response: %w", s.url+uri, err)
-	}
-	slices.SortStableFunc(running, func(i, j profiler.Goroutine) bool {
-		if iFirst, jFirst := i.ID == 1, j.ID == 1; iFirst != jFirst {
-			return iFirst
-		}
-		return i.Duration > j.Duration
-	})
+		return nil, fmt.Errorf("read %s response: %w", uri, err)
+	}
 	return running, nil
 }
@@ -0,0 +1,201 @@
+// Package order sorts goroutines by a user-selected key.
+package order
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/group"
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
+)
+
+// Key that goroutines are sorted by.
+type Key string
+
+const (
+	// KeyDefault sorts the main goroutine first, then by duration descending.
+	KeyDefault Key = ""
+	// KeyDuration sorts by blocked duration.
+	KeyDuration Key = "duration"
+	// KeyID sorts by goroutine ID.
+	KeyID Key = "id"
+	// KeyDepth sorts by call stack depth.
+	KeyDepth Key = "depth"
+	// KeyOp sorts by op, alphabetically.
+	KeyOp Key = "op"
+	// KeyFrame sorts by file and line of the top (innermost) project frame.
+	// Goroutines without project frames are sorted last.
+	KeyFrame Key = "frame"
+	// KeyGroup sorts by the number of goroutines with the same call stack.
+	KeyGroup Key = "group"
+)
+
+// Keys lists all sort keys, except KeyDefault.
+var Keys = []Key{KeyDuration, KeyID, KeyDepth, KeyOp, KeyFrame, KeyGroup}
+
+// Label returns a human-readable name of key.
+func (k Key) Label() string {
+	switch k {
+	case KeyDefault:
+		return "Default"
+	case KeyDuration:
+		return "Duration"
+	case KeyID:
+		return "ID"
+	case KeyDepth:
+		return "Stack depth"
+	case KeyOp:
+		return "Op"
+	case KeyFrame:
+		return "Top project frame"
+	case KeyGroup:
+		return "Group size"
+	default:
+		return string(k)
+	}
+}
+
+// Order of goroutines.
+type Order struct {
+	Key Key
+	// Desc sorts in descending order.
+	Desc bool
+}
+
+// Query parameters parsed by Parse.
+const (
+	// QuerySort is the Key.
+	QuerySort = "sort"
+	// QueryOrder is Asc or Desc. If empty, durations, depths and
+	// group sizes are sorted descending, other keys ascending.
+	QueryOrder = "order"
+	// Asc sorts in ascending order.
+	Asc = "asc"
+	// Desc sorts in descending order.
+	Desc = "desc"
+)
+
+// Parse reads the order from sort and order query parameters.
+func Parse(query url.Values) (Order, error) {
+	var o Order
+	switch key := Key(query.Get(QuerySort)); key {
+	case KeyDefault, KeyDuration, KeyID, KeyDepth, KeyOp, KeyFrame, KeyGroup:
+		o.Key = key
+	default:
+		return o, fmt.Errorf("invalid sort %q", key)
+	}
+	switch dir := query.Get(QueryOrder); dir {
+	case "":
+		o.Desc = o.Key == KeyDuration || o.Key == KeyDepth || o.Key == KeyGroup
+	case Asc:
+	case Desc:
+		o.Desc = true
+	default:
+		return o, fmt.Errorf("invalid order %q, expected %s or %s", dir, Asc, Desc)
+	}
+	return o, nil
+}
+
+// Sort goroutines in place. Group sizes are counted by call stack
+// signatures of mode, or group.ModeIgnoreArgs if it's group.ModeNone.
+// Goroutines with equal keys are sorted by ascending ID, so sorting
+// goroutines before grouping them also sorts groups by their first goroutine.
+func (o Order) Sort(goroutines []profiler.Goroutine, mode group.Mode) {
+	var cmp func(i, j profiler.Goroutine) int
+	switch o.Key {
+	case KeyDefault:
+		slices.SortStableFunc(goroutines, func(i, j profiler.Goroutine) bool {
+			if iFirst, jFirst := i.ID == 1, j.ID == 1; iFirst != jFirst {
+				return iFirst
+			}
+			return i.Duration > j.Duration
+		})
+		return
+	case KeyDuration:
+		cmp = func(i, j profiler.Goroutine) int { return compare(i.Duration, j.Duration) }
+	case KeyID:
+		cmp = func(i, j profiler.Goroutine) int { return compare(i.ID, j.ID) }
+	case KeyDepth:
+		cmp = func(i, j profiler.Goroutine) int { return compare(len(i.CallStack), len(j.CallStack)) }
+	case KeyOp:
+		cmp = func(i, j profiler.Goroutine) int { return compare(i.Op, j.Op) }
+	case KeyFrame:
+		frames := map[int]*profiler.CallStack{}
+		for i := range goroutines {
+			frames[goroutines[i].ID] = topProjectFrame(goroutines[i])
+		}
+		cmp = func(i, j profiler.Goroutine) int {
+			fi, fj := frames[i.ID], frames[j.ID]
+			if fi == nil || fj == nil {
+				// missing frames are last in both orders
+				c := compare(boolInt(fi == nil), boolInt(fj == nil))
+				if o.Desc {
+					return -c
+				}
+				return c
+			}
+			if c := compare(fi.File, fj.File); c != 0 {
+				return c
+			}
+			return compare(fi.Line, fj.Line)
+		}
+	case KeyGroup:
+		if mode == group.ModeNone {
+			mode = group.ModeIgnoreArgs
+		}
+		signatures := make(map[int]string, len(goroutines))
+		sizes := map[string]int{}
+		for _, gr := range goroutines {
+			sig := group.Signature(gr, mode)
+			signatures[gr.ID] = sig
+			sizes[sig]++
+		}
+		cmp = func(i, j profiler.Goroutine) int {
+			if c := compare(sizes[signatures[i.ID]], sizes[signatures[j.ID]]); c != 0 {
+				return c
+			}
+			// keep groups together
+			return compare(signatures[i.ID], signatures[j.ID])
+		}
+	}
+	slices.SortFunc(goroutines, func(i, j profiler.Goroutine) bool {
+		c := cmp(i, j)
+		if o.Desc {
+			c = -c
+		}
+		if c == 0 {
+			return i.ID < j.ID
+		}
+		return c < 0
+	})
+}
+
+// topProjectFrame returns the innermost project frame of gr, or nil.
+func topProjectFrame(gr profiler.Goroutine) *profiler.CallStack {
+	for i := len(gr.CallStack) - 1; i >= 0; i-- {
+		if gr.CallStack[i].Root == profiler.RootTypeProject {
+			return &gr.CallStack[i]
+		}
+	}
+	return nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compare[T constraints.Ordered](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
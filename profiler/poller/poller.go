@@ -0,0 +1,106 @@
+// Package poller periodically fetches running goroutines,
+// while at least one subscriber is interested in them.
+package poller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofu/gomon/profiler"
+)
+
+// DefaultInterval between polls, if not set.
+const DefaultInterval = 2 * time.Second
+
+// Snapshot of running goroutines.
+type Snapshot struct {
+	// Time when the goroutines were fetched.
+	Time time.Time
+	// Goroutines are shared between subscribers, and must not be modified.
+	Goroutines []profiler.Goroutine
+	// Err is set if fetching goroutines failed.
+	Err error
+}
+
+// Poller fetches goroutines from a profiler every interval, and sends
+// them to subscribers. Polling stops when the last subscriber leaves.
+type Poller struct {
+	prof     profiler.Profiler
+	interval time.Duration
+	mu       sync.Mutex
+	subs     map[chan Snapshot]struct{}
+	// stop is non-nil while polling
+	stop chan struct{}
+	// last snapshot, sent to new subscribers
+	last *Snapshot
+}
+
+// New requires non-nil profiler. If interval is 0, DefaultInterval is used.
+func New(prof profiler.Profiler, interval time.Duration) *Poller {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Poller{prof: prof, interval: interval, subs: map[chan Snapshot]struct{}{}}
+}
+
+// Interval returns the duration between polls.
+func (p *Poller) Interval() time.Duration { return p.interval }
+
+// Subscribe returns a channel receiving snapshots, starting with the
+// latest one. Slow subscribers miss intermediate snapshots. The returned
+// function unsubscribes, and must be called.
+func (p *Poller) Subscribe() (<-chan Snapshot, func()) {
+	ch := make(chan Snapshot, 1)
+	p.mu.Lock()
+	p.subs[ch] = struct{}{}
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+		go p.run(p.stop)
+	} else if p.last != nil {
+		ch <- *p.last
+	}
+	p.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			delete(p.subs, ch)
+			if len(p.subs) == 0 {
+				close(p.stop)
+				p.stop, p.last = nil, nil
+			}
+		})
+	}
+}
+
+func (p *Poller) run(stop chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		snap := Snapshot{Time: time.Now()}
+		snap.Goroutines, snap.Err = p.prof.Goroutines()
+		p.mu.Lock()
+		select {
+		case <-stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		p.last = &snap
+		for ch := range p.subs {
+			// replace an unread snapshot
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- snap
+		}
+		p.mu.Unlock()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
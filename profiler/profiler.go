@@ -2,6 +2,8 @@
 package profiler
 
 import (
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,7 +17,8 @@ const (
 	RootTypeGoRoot RootType = "GOROOT"
 	// RootTypeGoPath represents GOPATH.
 	RootTypeGoPath RootType = "GOPATH"
-	// RootTypeCGo represents linked CGO.
+	// RootTypeCGo represents linked CGO, generated in a build directory.
+	// Its file paths are absolute.
 	RootTypeCGo RootType = "CGO"
 )
 
@@ -36,21 +39,72 @@ type Highlight struct {
 	Suffix string `json:"suffix,omitempty"`
 }
 
+// Mismatch describes local source code that does not match a call stack frame.
+type Mismatch struct {
+	// Warning describes the difference, eg. the frame line
+	// being in a different function than expected.
+	Warning string `json:"warning"`
+	// Offset is the suggested line offset where the expected
+	// function was found in local source, or 0 if unknown.
+	Offset int `json:"offset,omitempty"`
+}
+
+// Blame describes the last change of a source code line.
+type Blame struct {
+	// Commit hash of the change.
+	Commit string `json:"commit"`
+	// Author name and email of the change.
+	Author string `json:"author"`
+	Email  string `json:"email,omitempty"`
+	// Date the change was authored.
+	Date time.Time `json:"date"`
+	// Summary is the first line of the commit message.
+	Summary string `json:"summary"`
+}
+
 // CallStack contains a running goroutine's caller stack info.
 type CallStack struct {
 	// FileLine contains caller's position in file/line.
 	FileLine
-	// Caller is true for every first goroutine in stack.
-	// The only exception is the main goroutine.
+	// Abs is the absolute path of File in the local environment, if known.
+	Abs string `json:"abs,omitempty"`
+	// Caller is true for the "created by" frame, that started the goroutine.
+	// It's the first frame of every goroutine, except the main goroutine.
 	Caller bool `json:"caller"`
 	// Package name, as seen by the Go source code.
 	Package string `json:"package"`
-	// Method name, eg. package.(*Server).ServeHTTP.
+	// Method name, eg. package.(*Server).ServeHTTP. Since Go 1.21, Caller
+	// methods are suffixed with the parent goroutine, eg. " in goroutine 13".
 	Method string `json:"method"`
 	Args   string `json:"args,omitempty"`
 	Extra  string `json:"extra,omitempty"`
 	// Highlight is optionally present.
 	Highlight
+	// Mismatch is set if local source code does not match this frame.
+	Mismatch *Mismatch `json:"mismatch,omitempty"`
+	// Blame is the last change of a project line, if known.
+	Blame *Blame `json:"blame,omitempty"`
+}
+
+// CreatedInPrefix precedes the parent goroutine ID in Caller methods, since Go 1.21.
+const CreatedInPrefix = " in goroutine "
+
+// CutCreatedIn returns method without the " in goroutine N" suffix
+// of Caller methods, and the parent goroutine ID N, or 0 without it.
+func CutCreatedIn(method string) (string, int) {
+	i := strings.LastIndex(method, CreatedInPrefix)
+	if i == -1 {
+		return method, 0
+	}
+	id := method[i+len(CreatedInPrefix):]
+	if len(id) == 0 || strings.Trim(id, "0123456789") != "" {
+		return method, 0
+	}
+	parent, err := strconv.Atoi(id)
+	if err != nil {
+		return method, 0
+	}
+	return method[:i], parent
 }
 
 // Goroutine and call stack information.
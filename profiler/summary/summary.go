@@ -0,0 +1,142 @@
+// Package summary aggregates goroutines into counts by op,
+// blocked duration, package, project line and root type.
+package summary
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/filter"
+)
+
+// Count of goroutines with the same Name.
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Bucket counts goroutines blocked for at least Min, and less than Max.
+type Bucket struct {
+	Min time.Duration `json:"min"`
+	// Max is 0 for the last, unbounded bucket.
+	Max   time.Duration `json:"max,omitempty"`
+	Count int           `json:"count"`
+}
+
+// Leaf counts goroutines whose innermost project frame is at the same line.
+type Leaf struct {
+	// Func is the function name, eg. "main.worker".
+	Func string `json:"func"`
+	profiler.FileLine
+	Count int `json:"count"`
+}
+
+// Summary of goroutines. Counts are sorted by most goroutines first.
+type Summary struct {
+	// Total number of goroutines.
+	Total int `json:"total"`
+	// Ops counts goroutines by op, eg. "chan receive".
+	Ops []Count `json:"ops"`
+	// Durations counts goroutines by blocked duration,
+	// up to the last non-empty bucket.
+	Durations []Bucket `json:"durations"`
+	// Packages lists the most common packages with goroutines passing through them.
+	Packages []Count `json:"packages"`
+	// Leaves lists the most common innermost project frames.
+	Leaves []Leaf `json:"leaves"`
+	// Roots counts goroutines passing through frames of each profiler.RootType.
+	// Frames without a root type are not counted.
+	Roots []Count `json:"roots"`
+}
+
+// Goroutines returns the summary of gs. Durations are bucketed by
+// ascending bounds, and at most top packages and leaves are listed.
+func Goroutines(gs []profiler.Goroutine, bounds []time.Duration, top int) Summary {
+	s := Summary{Total: len(gs)}
+	ops := map[string]int{}
+	packages := map[string]int{}
+	roots := map[string]int{}
+	leaves := map[string]*Leaf{}
+	s.Durations = make([]Bucket, len(bounds)+1)
+	for i := range s.Durations {
+		if i != 0 {
+			s.Durations[i].Min = bounds[i-1]
+		}
+		if i != len(bounds) {
+			s.Durations[i].Max = bounds[i]
+		}
+	}
+	for _, gr := range gs {
+		ops[gr.Op]++
+		s.Durations[sort.Search(len(bounds), func(i int) bool { return gr.Duration < bounds[i] })].Count++
+		seenPackages := map[string]struct{}{}
+		seenRoots := map[profiler.RootType]struct{}{}
+		for _, f := range gr.CallStack {
+			if _, ok := seenPackages[f.Package]; !ok {
+				seenPackages[f.Package] = struct{}{}
+				packages[f.Package]++
+			}
+			if _, ok := seenRoots[f.Root]; !ok && len(f.Root) != 0 {
+				seenRoots[f.Root] = struct{}{}
+				roots[string(f.Root)]++
+			}
+		}
+		for i := len(gr.CallStack) - 1; i >= 0; i-- {
+			f := gr.CallStack[i]
+			if f.Root != profiler.RootTypeProject {
+				continue
+			}
+			key := f.File + ":" + strconv.Itoa(f.Line)
+			if l, ok := leaves[key]; ok {
+				l.Count++
+			} else {
+				leaves[key] = &Leaf{Func: filter.Func(f), FileLine: f.FileLine, Count: 1}
+			}
+			break
+		}
+	}
+	for len(s.Durations) != 0 && s.Durations[len(s.Durations)-1].Count == 0 {
+		s.Durations = s.Durations[:len(s.Durations)-1]
+	}
+	s.Ops = counts(ops, 0)
+	s.Packages = counts(packages, top)
+	s.Roots = counts(roots, 0)
+	s.Leaves = make([]Leaf, 0, len(leaves))
+	for _, l := range leaves {
+		s.Leaves = append(s.Leaves, *l)
+	}
+	sort.Slice(s.Leaves, func(i, j int) bool {
+		a, b := s.Leaves[i], s.Leaves[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	if top != 0 && len(s.Leaves) > top {
+		s.Leaves = s.Leaves[:top]
+	}
+	return s
+}
+
+// counts returns up to limit counts of m, all if limit is 0.
+func counts(m map[string]int, limit int) []Count {
+	c := make([]Count, 0, len(m))
+	for name, count := range m {
+		c = append(c, Count{Name: name, Count: count})
+	}
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].Count != c[j].Count {
+			return c[i].Count > c[j].Count
+		}
+		return c[i].Name < c[j].Name
+	})
+	if limit != 0 && len(c) > limit {
+		c = c[:limit]
+	}
+	return c
+}
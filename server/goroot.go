@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofu/gomon/env/toolchain"
+	"github.com/gofu/gomon/profiler/httpprofiler"
+)
+
+// GoVersionOff disables local Go toolchain lookup, when used as Server.GoVersion.
+const GoVersionOff = "off"
+
+// setGoVersion suggests configuring the remote Go version,
+// if it cannot be detected.
+const setGoVersion = "set -remote-go-version or -buildinfo-url"
+
+// goVersionTimeout bounds detecting the remote Go version, so an
+// unreachable remote does not block startup.
+const goVersionTimeout = 10 * time.Second
+
+// matchGoRoot returns GOROOT of the local Go toolchain matching the remote
+// Go version, or conf.Local.GoRoot if the toolchain cannot be found.
+func matchGoRoot(ctx context.Context, conf Server, prof *httpprofiler.Profiler) string {
+	if conf.GoVersion == GoVersionOff {
+		return conf.Local.GoRoot
+	}
+	ctx, cancel := context.WithTimeout(ctx, goVersionTimeout)
+	defer cancel()
+	version, err := remoteGoVersion(ctx, conf, prof)
+	if err != nil {
+		log.Printf("Unknown remote Go version, using GOROOT %s: %s", conf.Local.GoRoot, err)
+		return conf.Local.GoRoot
+	}
+	if local, err := toolchain.GoRootVersion(conf.Local.GoRoot); err == nil && local == version {
+		return conf.Local.GoRoot
+	}
+	goRoot, err := toolchain.Find(version)
+	if err != nil {
+		log.Printf("Using GOROOT %s: %s", conf.Local.GoRoot, err)
+		return conf.Local.GoRoot
+	}
+	log.Printf("Using %s GOROOT %s", version, goRoot)
+	return goRoot
+}
+
+// remoteGoVersion returns the configured Go version, or detects it from
+// the remote build info page, or from the binary in remote command-line,
+// if the remote process runs on this machine.
+func remoteGoVersion(ctx context.Context, conf Server, prof *httpprofiler.Profiler) (string, error) {
+	if len(conf.GoVersion) != 0 {
+		return conf.GoVersion, nil
+	}
+	if len(conf.BuildInfoURL) != 0 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, conf.BuildInfoURL, nil)
+		if err != nil {
+			return "", err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		data, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("read %s response: %w", conf.BuildInfoURL, err)
+		}
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return "", fmt.Errorf("get %s: unexpected status %s", conf.BuildInfoURL, res.Status)
+		}
+		return toolchain.ParseVersion(data)
+	}
+	if !isLoopback(prof.Source()) {
+		return "", fmt.Errorf("remote binary of %s is not readable locally, %s", prof.Source(), setGoVersion)
+	}
+	args, err := prof.Cmdline(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 || len(args[0]) == 0 {
+		return "", fmt.Errorf("empty remote command-line")
+	}
+	if !filepath.IsAbs(args[0]) {
+		// resolved against the remote working directory or PATH, not ours
+		return "", fmt.Errorf("remote binary path %q is not absolute, %s", args[0], setGoVersion)
+	}
+	version, err := toolchain.BinaryVersion(args[0])
+	if err != nil {
+		return "", fmt.Errorf("read remote binary build info: %w", err)
+	}
+	// strip experiments, eg. "go1.21.3 X:boringcrypto"
+	version, _, _ = strings.Cut(version, " ")
+	return version, nil
+}
+
+// isLoopback reports whether rawURL is served by this machine.
+func isLoopback(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
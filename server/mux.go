@@ -4,21 +4,42 @@ import (
 	"net/http"
 	"net/http/pprof"
 
+	"github.com/gofu/gomon/editor"
+	"github.com/gofu/gomon/env/gitblame"
 	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/http/cachehandler"
+	"github.com/gofu/gomon/http/filehandler"
+	"github.com/gofu/gomon/http/flamehandler"
 	"github.com/gofu/gomon/http/htmlhandler"
 	"github.com/gofu/gomon/http/indexhandler"
 	"github.com/gofu/gomon/http/jsonhandler"
+	"github.com/gofu/gomon/http/livehandler"
 	"github.com/gofu/gomon/http/router"
 	"github.com/gofu/gomon/http/statichandler"
+	"github.com/gofu/gomon/http/summaryhandler"
 	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/history"
+	"github.com/gofu/gomon/profiler/poller"
 )
 
 // NewServeMux returns an http.Handler that handles the following pages:
 //   - GET /debug/pprof - net/http/pprof handler, plaintext
-//   - GET /json - list all goroutines, JSON
-//   - GET /html?min&max&markup&lines - list all goroutines, HTML
+//   - GET /json?min&max&include&exclude&match&sort&order&offset&limit - list all goroutines, JSON array; a page object with offset or limit
+//   - GET /html?min&max&include&exclude&match&sort&order&offset&limit&markup&lines&frames&group&theme - list all goroutines, HTML
+//   - GET /goroutine/{id}[.json]?lines&frames&theme - single goroutine and related ones, HTML or JSON
+//   - GET /live?min&max&include&exclude&match&sort&order&offset&limit - live goroutines, HTML and text/event-stream
+//   - GET /flame?min&max&include&exclude&match - flame graph of call stacks, HTML
+//   - GET /summary[.json]?min&max&include&exclude&match - counts by op, duration, package, line and root, HTML or JSON
+//   - GET /source?root&file&line - highlighted source file, HTML
+//   - GET /theme.css?theme - stylesheet of highlighted source code
+//   - GET /cache - source cache statistics, JSON; POST flushes the cache
 //   - GET / - list all routes
-func NewServeMux(hl highlight.Highlighter, prof profiler.Profiler) *http.ServeMux {
+//
+// Frames are resolved to local files, and linked to an editor, by linker.
+// If blamer is non-nil, project frames are annotated with git blame.
+// The live page receives goroutines polled by live. If hist is non-nil,
+// the index and summary pages chart its goroutine counts.
+func NewServeMux(hl highlight.Highlighter, prof profiler.Profiler, linker editor.Linker, blamer gitblame.Blamer, live *poller.Poller, hist *history.History) *http.ServeMux {
 	routes := router.Default
 	mux := http.NewServeMux()
 	mux.HandleFunc(routes.PProf, pprof.Index)
@@ -27,16 +48,35 @@ func NewServeMux(hl highlight.Highlighter, prof profiler.Profiler) *http.ServeMu
 	mux.HandleFunc(routes.PProf+"symbol", pprof.Symbol)
 	mux.HandleFunc(routes.PProf+"trace", pprof.Trace)
 	mux.Handle(statichandler.FaviconURL, statichandler.Handler{})
-	mux.Handle(routes.JSON, jsonhandler.New(prof))
-	mux.Handle(routes.HTML, htmlhandler.New(hl, prof))
+	mux.Handle(statichandler.ThemeURL, statichandler.Handler{})
+	verifier, _ := hl.(highlight.Verifier)
+	mux.Handle(routes.JSON, jsonhandler.New(prof, verifier, linker, blamer))
+	html := htmlhandler.New(hl, prof, routes, linker, blamer)
+	mux.Handle(routes.HTML, html)
+	mux.Handle(routes.Goroutine, html.Goroutine())
+	mux.Handle(routes.Live, livehandler.New(live, routes))
+	mux.Handle(routes.Flame, flamehandler.New(prof, routes))
+	summary := summaryhandler.New(prof, routes, hist)
+	mux.Handle(routes.Summary, summary)
+	mux.Handle(routes.Summary+".json", summary)
+	mux.Handle(routes.Source, filehandler.New(hl, prof))
+	links := []indexhandler.Link{
+		{Text: "index", HREF: routes.Index, Description: "this page"},
+		{Text: "HTML", HREF: routes.HTML, Description: "running goroutines in HTML format"},
+		{Text: "JSON", HREF: routes.JSON, Description: "running goroutines in JSON format"},
+		{Text: "live", HREF: routes.Live, Description: "running goroutines updated live"},
+		{Text: "flame", HREF: routes.Flame, Description: "flame graph of running goroutines"},
+		{Text: "summary", HREF: routes.Summary, Description: "overview of running goroutines, also in JSON"},
+	}
+	if cache, ok := hl.(cachehandler.Cache); ok {
+		mux.Handle(routes.Cache, cachehandler.New(cache))
+		links = append(links, indexhandler.Link{Text: "cache", HREF: routes.Cache, Description: "source cache statistics, POST to flush"})
+	}
+	links = append(links, indexhandler.Link{Text: "pprof", HREF: routes.PProf, Description: "debug profiler"})
 	index := indexhandler.Data{
 		ProfilerSource: prof.Source(),
-		Links: []indexhandler.Link{
-			{"index", routes.Index, "this page"},
-			{"HTML", routes.HTML, "running goroutines in HTML format"},
-			{"JSON", routes.JSON, "running goroutines in JSON format"},
-			{"pprof", routes.PProf, "debug profiler"},
-		},
+		Links:          links,
+		History:        hist,
 	}
 	mux.Handle(routes.Index, indexhandler.New(index))
 	return mux
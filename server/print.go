@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/gofu/gomon/editor"
+	"github.com/gofu/gomon/highlight"
+	"github.com/gofu/gomon/highlight/highlightfs"
+	"github.com/gofu/gomon/profiler/group"
+	"github.com/gofu/gomon/profiler/httpprofiler"
+	"github.com/gofu/gomon/profiler/order"
+	"github.com/gofu/gomon/style"
+)
+
+// PrintOptions configure terminal output of Print.
+type PrintOptions struct {
+	// Lines is the number of source lines shown before/after each frame's
+	// line. Negative number disables source code.
+	Lines int
+	// TrueColor uses 24-bit colors, otherwise the 256-color palette.
+	TrueColor bool
+	// Theme of highlighted source code, see style.Get.
+	Theme string
+}
+
+// Print writes running goroutines to w, similar to Go tracebacks, with
+// source code of each frame highlighted for terminals. Call stacks are
+// printed in the same order as the HTML page.
+func Print(ctx context.Context, conf Server, opts PrintOptions, w io.Writer) error {
+	prof := httpprofiler.New(conf.PProfURL, conf.Remote.WithDefaults(conf.Local))
+	conf.Local.GoRoot = matchGoRoot(ctx, conf, prof)
+	fsys, err := newHighlighter(conf)
+	if err != nil {
+		return err
+	}
+	hl := highlightfs.Terminal{
+		FS:     fsys,
+		Format: highlight.Terminal{TrueColor: opts.TrueColor, Style: style.Get(opts.Theme)},
+	}
+	running, err := prof.Goroutines()
+	if err != nil {
+		return err
+	}
+	order.Order{}.Sort(running, group.ModeNone)
+	linker := editor.Linker{Env: conf.Local}
+	bw := bufio.NewWriter(w)
+	for _, gr := range running {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(bw, "goroutine %d [%s", gr.ID, gr.Op)
+		if gr.Duration != 0 {
+			_, _ = fmt.Fprintf(bw, ", %s", gr.Duration)
+		}
+		_, _ = fmt.Fprint(bw, "]:\n")
+		for _, s := range gr.CallStack {
+			file := linker.Abs(s.FileLine)
+			if len(file) == 0 {
+				file = s.File
+			}
+			if s.Caller {
+				_, _ = fmt.Fprintf(bw, "created by %s.%s\n", s.Package, s.Method)
+			} else {
+				_, _ = fmt.Fprintf(bw, "%s.%s(%s)\n", s.Package, s.Method, s.Args)
+			}
+			_, _ = fmt.Fprintf(bw, "\t%s:%d %s\n", file, s.Line, s.Extra)
+			err = hl.Highlight(s.FileLine, highlight.Options{WrapSize: opts.Lines}, &s.Highlight)
+			if err != nil {
+				_, _ = fmt.Fprintf(bw, "\t%s\n", err)
+				continue
+			}
+			_, _ = fmt.Fprint(bw, s.Prefix, s.Suffix)
+		}
+		_, _ = fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
@@ -4,14 +4,27 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/gofu/gomon/editor"
 	"github.com/gofu/gomon/env"
+	"github.com/gofu/gomon/env/gitblame"
+	"github.com/gofu/gomon/highlight/archivefs"
+	"github.com/gofu/gomon/highlight/godef"
 	"github.com/gofu/gomon/highlight/highlightfs"
+	"github.com/gofu/gomon/highlight/remotefs"
+	"github.com/gofu/gomon/http/filehandler"
+	"github.com/gofu/gomon/http/router"
+	"github.com/gofu/gomon/profiler"
+	"github.com/gofu/gomon/profiler/history"
 	"github.com/gofu/gomon/profiler/httpprofiler"
+	"github.com/gofu/gomon/profiler/poller"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -26,23 +39,90 @@ type Server struct {
 	// Remote environment info, used to map results of PProfURL
 	// to Local environment for highlighting. May be empty.
 	Remote env.Env
+	// SourceArchive is an optional .zip/.tar.gz source archive path.
+	// If set, project sources are read from the archive instead of Local.Root.
+	SourceArchive string
+	// Archive contains root paths inside SourceArchive. Root defaults to
+	// the archive root, while empty GoRoot/GoPath fall back to Local.
+	Archive env.Env
+	// SourceURL is an optional remote http/sourcehandler URL. If set, sources
+	// are read from the remote process first, then from local sources.
+	SourceURL string
+	// GoVersion of the remote binary, eg. "go1.21.3", used to find a matching
+	// local Go toolchain for GOROOT sources. If empty, it's detected from
+	// BuildInfoURL, or the binary in the command-line of a remote on this
+	// machine; GoVersionOff disables the lookup.
+	GoVersion string
+	// CacheSize is the maximum total size of cached source files in bytes.
+	// If 0, highlightfs.DefaultCacheSize is used.
+	CacheSize int64
+	// BuildInfoURL optionally serves remote (*debug.BuildInfo).String output.
+	BuildInfoURL string
+	// GitBlame annotates project frames with the last change of their
+	// line, if Local.Root is within a git repository.
+	GitBlame bool
+	// GoToDefinition type-checks project sources in Local.Root, to link
+	// identifiers in highlighted source code to their definitions.
+	GoToDefinition bool
+	// Editor is an editor.Presets name, or a custom editor.Template,
+	// used to link frames to local files. Disabled if empty.
+	Editor string
+	// LiveInterval between polls of the live page, while it's open.
+	// If 0, poller.DefaultInterval is used.
+	LiveInterval time.Duration
+	// History is the retention of goroutine counts, polled every
+	// HistoryInterval, and charted on the index and summary pages.
+	// Disabled if 0.
+	History time.Duration
+	// HistoryInterval between polls recorded in History.
+	// If 0, history.DefaultInterval is used.
+	HistoryInterval time.Duration
 }
 
 // ListenAndServe starts an HTTP server on configured address, showing running
 // goroutines and their call stack context, fetched from .go source files.
 // Canceling ctx stops the server, and returns ctx.Err().
 func ListenAndServe(ctx context.Context, conf Server) error {
+	editorTemplate, err := editor.Parse(conf.Editor)
+	if err != nil {
+		return err
+	}
+	prof := httpprofiler.New(conf.PProfURL, conf.Remote.WithDefaults(conf.Local))
+	conf.Local.GoRoot = matchGoRoot(ctx, conf, prof)
+	hl, err := newHighlighter(conf)
+	if err != nil {
+		return err
+	}
+	if conf.GoToDefinition {
+		loadDefinitions(ctx, conf.Local, hl)
+	}
+	linker := editor.Linker{Env: conf.Local, Template: editorTemplate}
+	var blamer gitblame.Blamer
+	if conf.GitBlame {
+		repo, err := gitblame.New(conf.Local.Root)
+		if err != nil {
+			log.Printf("Git blame disabled: %v", err)
+		} else {
+			blamer = repo
+		}
+	}
 	ln, err := net.Listen("tcp", conf.Addr)
 	if err != nil {
 		return err
 	}
 	log.Printf("Listening on http://%s", ln.Addr())
 	group, ctx := errgroup.WithContext(ctx)
-	prof := httpprofiler.New(conf.PProfURL, conf.Remote.WithDefaults(conf.Local))
-	hl := &highlightfs.FS{Env: conf.Local}
+	var hist *history.History
+	if conf.History > 0 {
+		hist = history.New(conf.HistoryInterval, conf.History)
+		group.Go(func() error {
+			hist.Record(ctx, prof)
+			return nil
+		})
+	}
 	srv := &http.Server{
 		Addr:              ln.Addr().String(),
-		Handler:           NewServeMux(hl, prof),
+		Handler:           NewServeMux(hl, prof, linker, blamer, poller.New(prof, conf.LiveInterval), hist),
 		ReadHeaderTimeout: 10 * time.Second,
 		IdleTimeout:       2 * time.Minute,
 		BaseContext:       func(net.Listener) context.Context { return ctx },
@@ -61,3 +141,69 @@ func ListenAndServe(ctx context.Context, conf Server) error {
 	})
 	return group.Wait()
 }
+
+// loadDefinitions links identifiers highlighted by hl to their definitions,
+// once project sources in local.Root are type-checked in the background.
+func loadDefinitions(ctx context.Context, local env.Env, hl *highlightfs.FS) {
+	index := &godef.Index{
+		Env: local,
+		URL: func(file profiler.FileLine) string { return filehandler.URL(router.Default.Source, file) },
+	}
+	hl.Anchors = index
+	go func() {
+		start := time.Now()
+		if err := index.Load(ctx); err != nil {
+			log.Printf("Go to definition disabled: %v", err)
+			return
+		}
+		log.Printf("Type-checked project sources in %s", time.Since(start).Round(time.Millisecond))
+		// cached segments were highlighted without definitions
+		hl.Flush()
+	}()
+}
+
+// newHighlighter returns highlighter reading source files from the local
+// filesystem. Project sources in conf.SourceArchive replace local ones, while
+// sources served from conf.SourceURL take precedence over both.
+func newHighlighter(conf Server) (*highlightfs.FS, error) {
+	if len(conf.SourceArchive) == 0 && len(conf.SourceURL) == 0 {
+		return &highlightfs.FS{Env: conf.Local, CacheSize: conf.CacheSize}, nil
+	}
+	rootTypes := []profiler.RootType{profiler.RootTypeProject, profiler.RootTypeGoRoot, profiler.RootTypeGoPath}
+	roots := highlightfs.RootFS{}
+	for _, root := range rootTypes {
+		if local := conf.Local.RootPath(root); len(local) != 0 {
+			roots[root] = os.DirFS(local)
+		}
+	}
+	if len(conf.SourceArchive) != 0 {
+		archive, err := archivefs.Open(conf.SourceArchive)
+		if err != nil {
+			return nil, err
+		}
+		for _, root := range rootTypes {
+			dir := conf.Archive.RootPath(root)
+			if len(dir) == 0 && root != profiler.RootTypeProject {
+				continue
+			}
+			roots[root], err = archivefs.Sub(archive, dir)
+			if err != nil {
+				return nil, fmt.Errorf("archive %s root %q: %w", root, dir, err)
+			}
+		}
+	}
+	if len(conf.SourceURL) != 0 {
+		remote := remotefs.New(conf.SourceURL)
+		for _, root := range rootTypes {
+			sub, err := fs.Sub(remote, string(root))
+			if err != nil {
+				return nil, err
+			}
+			if roots[root] != nil {
+				sub = highlightfs.FallbackFS{sub, roots[root]}
+			}
+			roots[root] = sub
+		}
+	}
+	return &highlightfs.FS{FS: roots, Env: highlightfs.RootEnv, CacheSize: conf.CacheSize}, nil
+}
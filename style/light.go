@@ -0,0 +1,63 @@
+package style
+
+import (
+	"github.com/alecthomas/chroma"
+)
+
+var (
+	lightBg     = "#FAFAFA"
+	lightFg     = "#383A42"
+	lightGrey   = "#A0A1A7"
+	lightRed    = "#CA1243"
+	lightOrange = "#C18401"
+	lightGreen  = "#50A14F"
+	lightCyan   = "#0184BC"
+	lightBlue   = "#4078F2"
+	lightPurple = "#A626A4"
+)
+
+// Light is a light counterpart of Vulcan.
+var Light = chroma.MustNewStyle("light", chroma.StyleEntries{
+	chroma.Comment:               lightGrey + " italic",
+	chroma.CommentPreproc:        lightBlue,
+	chroma.CommentSpecial:        lightPurple + " italic",
+	chroma.Generic:               lightFg,
+	chroma.GenericDeleted:        lightRed,
+	chroma.GenericEmph:           "italic",
+	chroma.GenericError:          lightRed + " bold",
+	chroma.GenericHeading:        lightOrange + " bold",
+	chroma.GenericInserted:       lightGreen,
+	chroma.GenericOutput:         lightGrey,
+	chroma.GenericStrong:         "bold",
+	chroma.GenericSubheading:     lightRed + " italic",
+	chroma.GenericUnderline:      "underline",
+	chroma.Error:                 lightRed,
+	chroma.Keyword:               lightPurple,
+	chroma.KeywordConstant:       lightRed,
+	chroma.KeywordNamespace:      lightPurple,
+	chroma.KeywordType:           lightCyan + " bold",
+	chroma.Name:                  lightFg,
+	chroma.NameAttribute:         lightOrange,
+	chroma.NameBuiltin:           lightCyan,
+	chroma.NameClass:             lightOrange,
+	chroma.NameConstant:          lightOrange,
+	chroma.NameDecorator:         lightOrange,
+	chroma.NameException:         lightRed,
+	chroma.NameFunction:          lightBlue,
+	chroma.NameLabel:             lightRed,
+	chroma.NameTag:               lightRed,
+	chroma.NameVariable:          lightRed + " italic",
+	chroma.NameVariableGlobal:    lightOrange,
+	chroma.LiteralNumber:         lightOrange,
+	chroma.Operator:              lightCyan,
+	chroma.OperatorWord:          lightPurple,
+	chroma.Punctuation:           lightFg,
+	chroma.LiteralString:         lightGreen,
+	chroma.LiteralStringBacktick: lightGreen,
+	chroma.LiteralStringChar:     lightGreen,
+	chroma.LiteralStringEscape:   lightCyan,
+	chroma.LiteralStringRegex:    lightCyan,
+	chroma.Text:                  lightFg,
+	chroma.Background:            "bg:" + lightBg,
+	chroma.LineHighlight:         "bg:#E5E5E6",
+})
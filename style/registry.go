@@ -0,0 +1,38 @@
+package style
+
+import (
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+	"golang.org/x/exp/slices"
+)
+
+// Default theme name.
+const Default = "vulcan"
+
+// themes of this package, which take precedence
+// over chroma built-in styles of the same name.
+var themes = map[string]*chroma.Style{
+	Vulcan.Name: Vulcan,
+	Light.Name:  Light,
+}
+
+// Get returns a theme by name, either from this package or
+// a chroma built-in style. Returns nil if it's not found.
+func Get(name string) *chroma.Style {
+	if s, ok := themes[name]; ok {
+		return s
+	}
+	return styles.Registry[name]
+}
+
+// Names returns sorted names of all available themes.
+func Names() []string {
+	names := styles.Names()
+	for name := range themes {
+		if _, ok := styles.Registry[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
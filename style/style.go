@@ -19,6 +19,7 @@ var (
 	purple = "#BC74C4"
 )
 
+// Vulcan is the default dark theme.
 var Vulcan = chroma.MustNewStyle("vulcan", chroma.StyleEntries{
 	chroma.Comment:                  grey,
 	chroma.CommentHashbang:          grey + " italic",
@@ -91,6 +92,6 @@ var Vulcan = chroma.MustNewStyle("vulcan", chroma.StyleEntries{
 	chroma.LiteralStringSymbol:      green,
 	chroma.Text:                     white,
 	chroma.TextWhitespace:           white,
-	chroma.Background:               " bg: " + black,
+	chroma.Background:               "bg:" + black,
 	chroma.LineHighlight:            "bg:#2e2e2e",
 })